@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+)
+
+// projectLogWriter tees the build output into the log file of the project being built
+// and keeps the last lines of it in memory for the error summary.
+type projectLogWriter struct {
+	out      io.Writer
+	logDir   string
+	tailSize int
+
+	mu          sync.Mutex
+	projectName string
+	file        *os.File
+	tail        *tools.TailWriter
+	logPth      string          // Log file of the project being built
+	logPths     []string        // Log files in the order of their creation
+	loggedPths  map[string]bool // Set of logPths, a project may be built again after another one
+}
+
+func newProjectLogWriter(out io.Writer, logDir string, tailSize int) *projectLogWriter {
+	return &projectLogWriter{
+		out:      out,
+		logDir:   logDir,
+		tailSize: tailSize,
+		tail:     tools.NewTailWriter(tailSize),
+
+		loggedPths: map[string]bool{},
+	}
+}
+
+func projectLogFileName(projectName string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, projectName)
+	return name + "-build.log"
+}
+
+// startProject redirects the upcoming output into the given project's log file.
+func (w *projectLogWriter) startProject(projectName, commandStr string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.projectName != projectName {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+
+		pth := filepath.Join(w.logDir, projectLogFileName(projectName))
+		file, err := os.OpenFile(pth, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open build log (%s), error: %s", pth, err)
+		}
+
+		if out, ok := w.out.(*buildOutputEventWriter); ok {
+			out.startProject(projectName)
+		}
+
+		w.projectName = projectName
+		w.file = file
+		w.tail = tools.NewTailWriter(w.tailSize)
+		w.logPth = pth
+		if !w.loggedPths[pth] {
+			w.loggedPths[pth] = true
+			w.logPths = append(w.logPths, pth)
+		}
+	}
+
+	_, err := fmt.Fprintf(w.file, "$ %s\n", commandStr)
+	return err
+}
+
+// Write ...
+func (w *projectLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		if _, err := w.file.Write(p); err != nil {
+			return 0, err
+		}
+		if _, err := w.tail.Write(p); err != nil {
+			return 0, err
+		}
+	}
+
+	return w.out.Write(p)
+}
+
+// lastLines returns the last lines of the current project's output.
+func (w *projectLogWriter) lastLines() (string, []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.projectName, w.tail.Lines()
+}
+
+// currentLogPath returns the log file of the project being built, the projects are built one after the other,
+// so after a failed build it is the log of the project which failed.
+func (w *projectLogWriter) currentLogPath() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.logPth
+}
+
+// logPaths returns the log files created so far, each once.
+func (w *projectLogWriter) logPaths() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string{}, w.logPths...)
+}
+
+func (w *projectLogWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if out, ok := w.out.(*buildOutputEventWriter); ok {
+		out.close()
+	}
+
+	return w.closeFile()
+}
+
+func (w *projectLogWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
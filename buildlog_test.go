@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectLogWriterSwitchingBack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	w := newProjectLogWriter(ioutil.Discard, dir, 10)
+
+	steps := []struct {
+		projectName string
+		output      string
+	}{
+		{projectName: "App", output: "Build succeeded.\n"},
+		{projectName: "Lib", output: "Build succeeded.\n"},
+		{projectName: "App", output: "error : No installed provisioning profiles match the installed iOS signing identities.\n"},
+	}
+	for _, step := range steps {
+		if err := w.startProject(step.projectName, "msbuild "+step.projectName); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(step.output)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	appLogPth := filepath.Join(dir, "App-build.log")
+	libLogPth := filepath.Join(dir, "Lib-build.log")
+
+	if logPths := w.logPaths(); !reflect.DeepEqual(logPths, []string{appLogPth, libLogPth}) {
+		t.Errorf("logPaths() = %v, want %v", logPths, []string{appLogPth, libLogPth})
+	}
+
+	// the last project started is the one which failed
+	logPth := w.currentLogPath()
+	if logPth != appLogPth {
+		t.Fatalf("currentLogPath() = %s, want %s", logPth, appLogPth)
+	}
+
+	category, _, err := classifyBuildLog(logPth)
+	if err != nil {
+		t.Fatalf("classifyBuildLog() error = %s", err)
+	}
+	if category == nil || category.ID != "missing_provisioning_profile" {
+		t.Errorf("classifyBuildLog() = %+v, want missing_provisioning_profile", category)
+	}
+}
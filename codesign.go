@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/builder"
+)
+
+// distribution methods of the iOS and tvOS builds, distributionMethodProject leaves the code signing to the projects
+const (
+	distributionMethodProject     = "project"
+	distributionMethodDevelopment = "development"
+	distributionMethodAdHoc       = "ad-hoc"
+	distributionMethodEnterprise  = "enterprise"
+	distributionMethodAppStore    = "app-store"
+)
+
+var distributionMethods = []string{
+	distributionMethodProject,
+	distributionMethodDevelopment,
+	distributionMethodAdHoc,
+	distributionMethodEnterprise,
+	distributionMethodAppStore,
+}
+
+// codesign key prefixes, Xamarin.iOS picks the first matching identity of the keychain
+const (
+	developmentCodesignKey  = "iPhone Developer"
+	distributionCodesignKey = "iPhone Distribution"
+)
+
+// provisioningProfile is an installed .mobileprovision file.
+type provisioningProfile struct {
+	Pth                string
+	Name               string
+	UUID               string
+	TeamName           string
+	ExpirationDate     time.Time
+	DistributionMethod string
+}
+
+func (profile provisioningProfile) String() string {
+	return fmt.Sprintf("%s (%s, %s)", profile.Name, profile.UUID, profile.DistributionMethod)
+}
+
+func (profile provisioningProfile) isExpired(now time.Time) bool {
+	return !profile.ExpirationDate.IsZero() && profile.ExpirationDate.Before(now)
+}
+
+// provisioningProfilesDir returns the directory Xcode installs the provisioning profiles into.
+func provisioningProfilesDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Library", "MobileDevice", "Provisioning Profiles"), nil
+}
+
+// listProvisioningProfiles parses the provisioning profiles of the directory, a missing directory has none.
+func listProvisioningProfiles(dir string) ([]provisioningProfile, []string, error) {
+	warnings := []string{}
+
+	pths, err := filepath.Glob(filepath.Join(dir, "*.mobileprovision"))
+	if err != nil {
+		return nil, warnings, err
+	}
+	sort.Strings(pths)
+
+	profiles := []provisioningProfile{}
+	for _, pth := range pths {
+		profile, err := parseProvisioningProfile(pth)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to parse provisioning profile (%s), error: %s", pth, err))
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, warnings, nil
+}
+
+// parseProvisioningProfile reads the plist embedded in the signed (CMS) provisioning profile.
+func parseProvisioningProfile(pth string) (provisioningProfile, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return provisioningProfile{}, err
+	}
+
+	start := bytes.Index(content, []byte("<?xml"))
+	end := bytes.LastIndex(content, []byte("</plist>"))
+	if start == -1 || end < start {
+		return provisioningProfile{}, fmt.Errorf("no plist found")
+	}
+
+	plist, err := decodePlistDict(content[start : end+len("</plist>")])
+	if err != nil {
+		return provisioningProfile{}, fmt.Errorf("failed to decode plist, error: %s", err)
+	}
+
+	profile := provisioningProfile{
+		Pth:                pth,
+		DistributionMethod: profileDistributionMethod(plist),
+	}
+	profile.Name, _ = plist["Name"].(string)
+	profile.UUID, _ = plist["UUID"].(string)
+	profile.TeamName, _ = plist["TeamName"].(string)
+	profile.ExpirationDate, _ = plist["ExpirationDate"].(time.Time)
+
+	if profile.UUID == "" {
+		return provisioningProfile{}, fmt.Errorf("no UUID found")
+	}
+
+	return profile, nil
+}
+
+// profileDistributionMethod tells the distribution method of the profile:
+// enterprise profiles provision all devices, development and ad-hoc profiles list the devices
+// (only the development ones allow debugging), app store profiles have no devices.
+func profileDistributionMethod(plist map[string]interface{}) string {
+	if provisionsAllDevices, _ := plist["ProvisionsAllDevices"].(bool); provisionsAllDevices {
+		return distributionMethodEnterprise
+	}
+
+	if _, ok := plist["ProvisionedDevices"]; !ok {
+		return distributionMethodAppStore
+	}
+
+	entitlements, _ := plist["Entitlements"].(map[string]interface{})
+	if getTaskAllow, _ := entitlements["get-task-allow"].(bool); getTaskAllow {
+		return distributionMethodDevelopment
+	}
+
+	return distributionMethodAdHoc
+}
+
+// decodePlistDict decodes the root dict of an XML plist.
+func decodePlistDict(content []byte) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no root dict found")
+		} else if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "dict" {
+			value, err := decodePlistValue(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return value.(map[string]interface{}), nil
+		}
+	}
+}
+
+// decodePlistValue decodes the plist value starting with the given element,
+// dicts into map[string]interface{}, arrays into []interface{}, dates into time.Time.
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch element := token.(type) {
+			case xml.StartElement:
+				if element.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &element); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				value, err := decodePlistValue(decoder, element)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch element := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, element)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text := ""
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+
+	switch start.Name.Local {
+	case "integer":
+		return strconv.ParseInt(text, 10, 64)
+	case "date":
+		return time.Parse(time.RFC3339, text)
+	default:
+		// string, real and base64 data are kept as text
+		return text, nil
+	}
+}
+
+// resolveCodesignSettings validates the code signing inputs against the installed provisioning profiles
+// and returns the code signing properties of the iOS and tvOS builds.
+func resolveCodesignSettings(distributionMethod, codesignKey, provisioningProfileID, entitlementsPth string, profiles []provisioningProfile, now time.Time) (builder.CodesignSettings, *provisioningProfile, error) {
+	settings := builder.CodesignSettings{
+		Key:             codesignKey,
+		EntitlementsPth: entitlementsPth,
+	}
+
+	var profile *provisioningProfile
+	if provisioningProfileID != "" {
+		selected, err := selectProvisioningProfile(provisioningProfileID, distributionMethod, profiles, now)
+		if err != nil {
+			return builder.CodesignSettings{}, nil, err
+		}
+		profile = &selected
+
+		// the UUID is unambiguous, several profiles may have the same name
+		settings.Provision = profile.UUID
+		if distributionMethod == distributionMethodProject {
+			distributionMethod = profile.DistributionMethod
+		}
+	} else if distributionMethod != distributionMethodProject {
+		// Xamarin.iOS picks the profile matching the bundle ID and the codesign key
+		found := false
+		for _, profile := range profiles {
+			if profile.DistributionMethod == distributionMethod && !profile.isExpired(now) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return builder.CodesignSettings{}, nil, fmt.Errorf("no valid %s provisioning profile installed", distributionMethod)
+		}
+	}
+
+	if settings.Key == "" {
+		switch distributionMethod {
+		case distributionMethodProject:
+		case distributionMethodDevelopment:
+			settings.Key = developmentCodesignKey
+		default:
+			settings.Key = distributionCodesignKey
+		}
+	}
+
+	return settings, profile, nil
+}
+
+// selectProvisioningProfile returns the profile with the given UUID or name,
+// of the same named profiles the valid one fitting the distribution method, which expires last.
+func selectProvisioningProfile(id, distributionMethod string, profiles []provisioningProfile, now time.Time) (provisioningProfile, error) {
+	matching := []provisioningProfile{}
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.UUID, id) || profile.Name == id {
+			matching = append(matching, profile)
+		}
+	}
+
+	if len(matching) == 0 {
+		installed := []string{}
+		for _, profile := range profiles {
+			installed = append(installed, profile.String())
+		}
+		return provisioningProfile{}, fmt.Errorf("provisioning profile (%s) is not installed, installed profiles: %s", id, strings.Join(installed, ", "))
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].ExpirationDate.After(matching[j].ExpirationDate)
+	})
+
+	// if none of them is usable, the reason of the profile expiring last is reported
+	var firstErr error
+	for _, profile := range matching {
+		var err error
+		if profile.isExpired(now) {
+			err = fmt.Errorf("provisioning profile (%s) expired at %s", profile, profile.ExpirationDate.Format(time.RFC3339))
+		} else if distributionMethod != distributionMethodProject && profile.DistributionMethod != distributionMethod {
+			err = fmt.Errorf("provisioning profile (%s) does not fit the %s distribution method", profile, distributionMethod)
+		} else {
+			return profile, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return provisioningProfile{}, firstErr
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// provisioningProfileContent returns a mobileprovision plist with the given extra keys,
+// wrapped into binary bytes like the CMS signature of the real profiles.
+func provisioningProfileContent(name, uuid, expirationDate, extraKeys string) string {
+	return "0\x82\x1d\x8a\x06\t*\x86H\x86\xf7\r\x01\x07\x02" + `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>` + name + `</string>
+	<key>UUID</key>
+	<string>` + uuid + `</string>
+	<key>TeamName</key>
+	<string>Example Team</string>
+	<key>ExpirationDate</key>
+	<date>` + expirationDate + `</date>
+` + extraKeys + `</dict>
+</plist>` + "\xa0\x82\r\x1e0\x82\x04"
+}
+
+const (
+	provisionedDevicesKeys = `	<key>ProvisionedDevices</key>
+	<array>
+		<string>00008030-000000000000001E</string>
+	</array>
+`
+	getTaskAllowKeys = `	<key>Entitlements</key>
+	<dict>
+		<key>get-task-allow</key>
+		<true/>
+	</dict>
+`
+)
+
+func TestDecodePlistDict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "values",
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>String</key>
+	<string> value </string>
+	<key>Integer</key>
+	<integer>42</integer>
+	<key>Date</key>
+	<date>2026-01-02T03:04:05Z</date>
+	<key>True</key>
+	<true/>
+	<key>False</key>
+	<false/>
+	<key>Array</key>
+	<array>
+		<string>a</string>
+		<integer>1</integer>
+	</array>
+	<key>Dict</key>
+	<dict>
+		<key>Nested</key>
+		<true/>
+	</dict>
+	<key>Data</key>
+	<data>AAEC</data>
+</dict>
+</plist>`,
+			want: map[string]interface{}{
+				"String":  "value",
+				"Integer": int64(42),
+				"Date":    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				"True":    true,
+				"False":   false,
+				"Array":   []interface{}{"a", int64(1)},
+				"Dict":    map[string]interface{}{"Nested": true},
+				"Data":    "AAEC",
+			},
+		},
+		{
+			name:    "no root dict",
+			content: `<?xml version="1.0" encoding="UTF-8"?><plist version="1.0"><array/></plist>`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid integer",
+			content: `<plist version="1.0"><dict><key>Integer</key><integer>x</integer></dict></plist>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePlistDict([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodePlistDict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodePlistDict() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisioningProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraKeys  string
+		wantMethod string
+	}{
+		{
+			name:       "enterprise profile provisions all devices",
+			extraKeys:  "\t<key>ProvisionsAllDevices</key>\n\t<true/>\n",
+			wantMethod: distributionMethodEnterprise,
+		},
+		{
+			name:       "app store profile has no devices",
+			extraKeys:  getTaskAllowKeys,
+			wantMethod: distributionMethodAppStore,
+		},
+		{
+			name:       "development profile allows debugging",
+			extraKeys:  provisionedDevicesKeys + getTaskAllowKeys,
+			wantMethod: distributionMethodDevelopment,
+		},
+		{
+			name:       "ad-hoc profile lists the devices",
+			extraKeys:  provisionedDevicesKeys,
+			wantMethod: distributionMethodAdHoc,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "codesign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(dir, "profile.mobileprovision")
+			content := provisioningProfileContent("App Profile", "UUID-1", "2027-01-02T03:04:05Z", tt.extraKeys)
+			if err := ioutil.WriteFile(pth, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			profile, err := parseProvisioningProfile(pth)
+			if err != nil {
+				t.Fatalf("parseProvisioningProfile() error = %s", err)
+			}
+
+			want := provisioningProfile{
+				Pth:                pth,
+				Name:               "App Profile",
+				UUID:               "UUID-1",
+				TeamName:           "Example Team",
+				ExpirationDate:     time.Date(2027, 1, 2, 3, 4, 5, 0, time.UTC),
+				DistributionMethod: tt.wantMethod,
+			}
+			if !reflect.DeepEqual(profile, want) {
+				t.Errorf("parseProvisioningProfile() = %+v, want %+v", profile, want)
+			}
+		})
+	}
+}
+
+func TestSelectProvisioningProfile(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	expired := provisioningProfile{Name: "App", UUID: "UUID-EXPIRED", DistributionMethod: distributionMethodAppStore, ExpirationDate: now.AddDate(0, 0, -1)}
+	appStore := provisioningProfile{Name: "App", UUID: "UUID-APPSTORE", DistributionMethod: distributionMethodAppStore, ExpirationDate: now.AddDate(0, 1, 0)}
+	appStoreLater := provisioningProfile{Name: "App", UUID: "UUID-APPSTORE-LATER", DistributionMethod: distributionMethodAppStore, ExpirationDate: now.AddDate(1, 0, 0)}
+	adHoc := provisioningProfile{Name: "App", UUID: "UUID-ADHOC", DistributionMethod: distributionMethodAdHoc, ExpirationDate: now.AddDate(2, 0, 0)}
+	other := provisioningProfile{Name: "Other", UUID: "UUID-OTHER", DistributionMethod: distributionMethodDevelopment, ExpirationDate: now.AddDate(0, 0, -1)}
+
+	profiles := []provisioningProfile{expired, appStore, appStoreLater, adHoc, other}
+
+	tests := []struct {
+		name               string
+		id                 string
+		distributionMethod string
+		want               provisioningProfile
+		wantErr            string
+	}{
+		{
+			name:               "UUID",
+			id:                 "uuid-appstore",
+			distributionMethod: distributionMethodAppStore,
+			want:               appStore,
+		},
+		{
+			name:               "name, the latest expiry of the distribution method",
+			id:                 "App",
+			distributionMethod: distributionMethodAppStore,
+			want:               appStoreLater,
+		},
+		{
+			name:               "name, the latest expiry of any distribution method",
+			id:                 "App",
+			distributionMethod: distributionMethodProject,
+			want:               adHoc,
+		},
+		{
+			name:               "UUID of an expired profile",
+			id:                 "UUID-EXPIRED",
+			distributionMethod: distributionMethodAppStore,
+			wantErr:            "expired",
+		},
+		{
+			name:               "name without a profile of the distribution method",
+			id:                 "App",
+			distributionMethod: distributionMethodEnterprise,
+			wantErr:            "does not fit the enterprise distribution method",
+		},
+		{
+			name:               "not installed",
+			id:                 "Missing",
+			distributionMethod: distributionMethodAppStore,
+			wantErr:            "is not installed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectProvisioningProfile(tt.id, tt.distributionMethod, profiles, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("selectProvisioningProfile() error = %v, want error containing %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectProvisioningProfile() error = %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectProvisioningProfile() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
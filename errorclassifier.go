@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	buildErrorCategoryEnvKey = "BITRISE_XAMARIN_BUILD_ERROR_CATEGORY"
+
+	buildErrorCategoryUnknown = "unknown"
+	buildErrorCategoryTimeout = "timeout"
+
+	// maxMatchingLines is the number of matching log lines printed for a classified error.
+	maxMatchingLines = 5
+)
+
+// buildErrorCategory describes a known build failure and how to fix it.
+type buildErrorCategory struct {
+	ID          string
+	Description string
+	Hints       []string
+
+	patterns []*regexp.Regexp
+}
+
+func (category buildErrorCategory) matches(line string) bool {
+	for _, re := range category.patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildErrorCategories are ordered from the most specific to the least specific.
+var buildErrorCategories = []buildErrorCategory{
+	{
+		ID:          "missing_provisioning_profile",
+		Description: "No provisioning profile was found which matches the project's bundle identifier and code signing settings.",
+		Hints: []string{
+			"Upload the required provisioning profile to Bitrise and add the Certificate and profile installer Step before this Step.",
+			"Check that the CodesignProvision property of the project configuration references an installed profile.",
+			"Make sure the profile's App ID matches the CFBundleIdentifier in Info.plist.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)could not find any available provisioning profiles`),
+			regexp.MustCompile(`(?i)no installed provisioning profiles match`),
+			regexp.MustCompile(`(?i)could not find a valid provisioning profile`),
+			regexp.MustCompile(`(?i)provisioning profile .* not found`),
+		},
+	},
+	{
+		ID:          "missing_signing_identity",
+		Description: "No valid code signing identity (certificate with private key) was found in the keychain.",
+		Hints: []string{
+			"Upload the .p12 certificate export with its private key to Bitrise and add the Certificate and profile installer Step before this Step.",
+			"Check that the CodesignKey property of the project configuration matches the installed certificate's name.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)no valid (ios )?code signing keys found`),
+			regexp.MustCompile(`(?i)could not find a valid .* (distribution|developer) identity`),
+			regexp.MustCompile(`(?i)no (valid )?signing identit(y|ies)`),
+		},
+	},
+	{
+		ID:          "android_sdk_missing",
+		Description: "The Android SDK, NDK or one of their components could not be found.",
+		Hints: []string{
+			"Make sure the ANDROID_HOME (or ANDROID_SDK_ROOT) environment variable points to an installed Android SDK.",
+			"Install the SDK platform and build tools versions the project targets, for example with the Install missing Android SDK components Step.",
+			"If the project uses native code, make sure the Android NDK is installed and ANDROID_NDK_HOME is set.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\bXA5300\b`),
+			regexp.MustCompile(`\bXA5205\b`),
+			regexp.MustCompile(`\bXA5101\b`),
+			regexp.MustCompile(`(?i)android sdk directory could not be found`),
+			regexp.MustCompile(`(?i)could not locate the android ndk`),
+		},
+	},
+	{
+		ID:          "java_version_mismatch",
+		Description: "The installed Java Development Kit version is not supported by the Xamarin.Android build.",
+		Hints: []string{
+			"Select the JDK version required by your Xamarin.Android version, for example with the Set Java version Step.",
+			"Make sure JAVA_HOME points to the selected JDK.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\bXA003[0-2]\b`),
+			regexp.MustCompile(`(?i)unsupported major\.minor version`),
+			regexp.MustCompile(`java\.lang\.UnsupportedClassVersionError`),
+		},
+	},
+	{
+		ID:          "nuget_restore_needed",
+		Description: "The NuGet packages of the solution are not restored.",
+		Hints: []string{
+			"Add the NuGet Restore Step before this Step.",
+			"Check that every package source used by the solution is reachable from the build machine.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\bNETSDK1004\b`),
+			regexp.MustCompile(`(?i)project\.assets\.json'? not found`),
+			regexp.MustCompile(`(?i)references nuget package\(s\) that are missing`),
+		},
+	},
+	{
+		ID:          "linker_error",
+		Description: "Linking the native or managed code of the app failed.",
+		Hints: []string{
+			"Check the missing symbols in the log and make sure every native library and binding is built for the target architectures.",
+			"Try building with a less aggressive linker setting (Link SDK assemblies only) to find out if the managed linker removes required code.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\bMT2101\b`),
+			regexp.MustCompile(`\bMT521[0-9]\b`),
+			regexp.MustCompile(`\bXA2006\b`),
+			regexp.MustCompile(`ld: symbol\(s\) not found`),
+			regexp.MustCompile(`linker command failed`),
+		},
+	},
+	{
+		ID:          "xamarin_android_error",
+		Description: "The Xamarin.Android build failed with an XA error code.",
+		Hints: []string{
+			"Look up the error code at https://docs.microsoft.com/en-us/xamarin/android/errors-and-warnings/ for its cause and solution.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`error XA\d{4}`),
+		},
+	},
+	{
+		ID:          "xamarin_ios_error",
+		Description: "The Xamarin.iOS build failed with an MT error code.",
+		Hints: []string{
+			"Look up the error code at https://docs.microsoft.com/en-us/xamarin/ios/troubleshooting/mtouch-errors for its cause and solution.",
+		},
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`error MT\d{4}`),
+		},
+	},
+}
+
+// classifyBuildLog returns the first known error category found in the build log and the matching lines.
+func classifyBuildLog(logPth string) (*buildErrorCategory, []string, error) {
+	file, err := os.Open(logPth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open build log (%s), error: %s", logPth, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Warnf("Failed to close build log (%s), error: %s", logPth, err)
+		}
+	}()
+
+	matchingLinesByCategory := make([][]string, len(buildErrorCategories))
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		for i, category := range buildErrorCategories {
+			if len(matchingLinesByCategory[i]) < maxMatchingLines && category.matches(line) {
+				matchingLinesByCategory[i] = append(matchingLinesByCategory[i], line)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read build log (%s), error: %s", logPth, err)
+	}
+
+	for i, lines := range matchingLinesByCategory {
+		if len(lines) > 0 {
+			return &buildErrorCategories[i], lines, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// printBuildErrorCategory prints the explanation and remediation hints of the given category.
+func printBuildErrorCategory(category buildErrorCategory, matchingLines []string) {
+	log.Errorf("Error category: %s", category.ID)
+	log.Printf("%s", category.Description)
+
+	fmt.Println()
+	log.Printf("Matching log lines:")
+	for _, line := range matchingLines {
+		log.Printf("  %s", line)
+	}
+
+	fmt.Println()
+	log.Warnf("Hints:")
+	for _, hint := range category.Hints {
+		log.Warnf("- %s", hint)
+	}
+}
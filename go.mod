@@ -5,6 +5,5 @@ go 1.16
 require (
 	github.com/bitrise-io/go-steputils v0.0.0-20210527075147-910ce7a105a1
 	github.com/bitrise-io/go-utils v0.0.0-20210713111255-08be784d45d0
-	github.com/bitrise-io/go-xamarin v0.0.0-20210730080427-dade17b31965
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51
 )
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// nunitTestRun is the root element of an NUnit 3 test result file.
+type nunitTestRun struct {
+	XMLName    xml.Name         `xml:"test-run"`
+	TestSuites []nunitTestSuite `xml:"test-suite"`
+}
+
+type nunitTestSuite struct {
+	Type       string           `xml:"type,attr"`
+	Name       string           `xml:"name,attr"`
+	FullName   string           `xml:"fullname,attr"`
+	TestSuites []nunitTestSuite `xml:"test-suite"`
+	TestCases  []nunitTestCase  `xml:"test-case"`
+}
+
+type nunitTestCase struct {
+	Name      string  `xml:"name,attr"`
+	FullName  string  `xml:"fullname,attr"`
+	ClassName string  `xml:"classname,attr"`
+	Result    string  `xml:"result,attr"`
+	Duration  float64 `xml:"duration,attr"`
+	Failure   struct {
+		Message    string `xml:"message"`
+		StackTrace string `xml:"stack-trace"`
+	} `xml:"failure"`
+	Reason struct {
+		Message string `xml:"message"`
+	} `xml:"reason"`
+	Output string `xml:"output"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Content string `xml:",chardata"`
+}
+
+// convertNunitToJunit converts an NUnit 3 test result into JUnit test suites, one suite per test fixture.
+func convertNunitToJunit(nunitResult nunitTestRun) junitTestSuites {
+	var suites []junitTestSuite
+
+	var walk func(suite nunitTestSuite)
+	walk = func(suite nunitTestSuite) {
+		if len(suite.TestCases) > 0 {
+			suites = append(suites, convertNunitTestSuite(suite))
+		}
+		for _, child := range suite.TestSuites {
+			walk(child)
+		}
+	}
+
+	for _, suite := range nunitResult.TestSuites {
+		walk(suite)
+	}
+
+	return junitTestSuites{TestSuites: suites}
+}
+
+func convertNunitTestSuite(suite nunitTestSuite) junitTestSuite {
+	name := suite.FullName
+	if name == "" {
+		name = suite.Name
+	}
+
+	junitSuite := junitTestSuite{Name: name}
+
+	var duration float64
+	for _, testCase := range suite.TestCases {
+		junitCase := junitTestCase{
+			Name:      testCase.Name,
+			ClassName: testCase.ClassName,
+			Time:      formatSeconds(testCase.Duration),
+			SystemOut: testCase.Output,
+		}
+		if junitCase.ClassName == "" {
+			junitCase.ClassName = name
+		}
+
+		switch testCase.Result {
+		case "Failed":
+			junitCase.Failure = &junitMessage{Message: testCase.Failure.Message, Content: testCase.Failure.StackTrace}
+			junitSuite.Failures++
+		case "Skipped", "Inconclusive":
+			junitCase.Skipped = &junitMessage{Message: testCase.Reason.Message}
+			junitSuite.Skipped++
+		}
+
+		junitSuite.Tests++
+		duration += testCase.Duration
+		junitSuite.TestCases = append(junitSuite.TestCases, junitCase)
+	}
+	junitSuite.Time = formatSeconds(duration)
+
+	return junitSuite
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+// exportNunitResultAsTestReport converts the NUnit result file into the given test's Bitrise test report directory
+// and returns the number of tests and failed tests.
+func exportNunitResultAsTestReport(nunitResultPth, testDeployDir, testName string) (int, int, error) {
+	content, err := ioutil.ReadFile(nunitResultPth)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read test result (%s), error: %s", nunitResultPth, err)
+	}
+
+	var nunitResult nunitTestRun
+	if err := xml.Unmarshal(content, &nunitResult); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse test result (%s), error: %s", nunitResultPth, err)
+	}
+
+	junitResult := convertNunitToJunit(nunitResult)
+
+	tests, failures := 0, 0
+	for _, suite := range junitResult.TestSuites {
+		tests += suite.Tests
+		failures += suite.Failures
+	}
+
+	junitContent, err := xml.MarshalIndent(junitResult, "", "  ")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to serialize JUnit test result, error: %s", err)
+	}
+
+	testInfoContent, err := json.Marshal(map[string]string{"test-name": testName})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to serialize test info, error: %s", err)
+	}
+
+	reportDir := filepath.Join(testDeployDir, testName)
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return 0, 0, fmt.Errorf("failed to create test report dir (%s), error: %s", reportDir, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(reportDir, "TestResult.xml"), append([]byte(xml.Header), junitContent...), 0644); err != nil {
+		return 0, 0, fmt.Errorf("failed to write JUnit test result, error: %s", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(reportDir, "test-info.json"), testInfoContent, 0644); err != nil {
+		return 0, 0, fmt.Errorf("failed to write test info, error: %s", err)
+	}
+
+	return tests, failures, nil
+}
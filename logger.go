@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/builder"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logEvent is a structured log message, printed as a single line JSON object in json log format.
+type logEvent struct {
+	Timestamp string                 `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Event     string                 `json:"event"`
+	Message   string                 `json:"message,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// String ...
+func (e logEvent) String() string {
+	return e.Message
+}
+
+// JSON ...
+func (e logEvent) JSON() string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"level":"error","event":"message","message":"failed to serialize log event: %s"}`, err) + "\n"
+	}
+	return string(b) + "\n"
+}
+
+// stepLogger prints the Step's log either as colored text or as JSON events, one event per line.
+type stepLogger struct {
+	json        bool
+	jsonLogger  log.Logger
+	configGroup string
+}
+
+var logger = newStepLogger(logFormatText)
+
+func newStepLogger(format string) *stepLogger {
+	if format == logFormatJSON {
+		return &stepLogger{
+			json:       true,
+			jsonLogger: log.NewJSONLoger(os.Stdout),
+		}
+	}
+	return &stepLogger{}
+}
+
+func (l *stepLogger) event(level, name, message string, data map[string]interface{}) {
+	l.jsonLogger.Print(logEvent{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Level:     level,
+		Event:     name,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// Println prints an empty line in text format, to separate the log sections.
+func (l *stepLogger) Println() {
+	if !l.json {
+		fmt.Println()
+	}
+}
+
+// Infof ...
+func (l *stepLogger) Infof(format string, v ...interface{}) {
+	if !l.json {
+		log.Infof(format, v...)
+		return
+	}
+	l.event("info", "message", fmt.Sprintf(format, v...), nil)
+}
+
+// Printf ...
+func (l *stepLogger) Printf(format string, v ...interface{}) {
+	if !l.json {
+		log.Printf(format, v...)
+		return
+	}
+	l.event("info", "message", fmt.Sprintf(format, v...), nil)
+}
+
+// Donef ...
+func (l *stepLogger) Donef(format string, v ...interface{}) {
+	if !l.json {
+		log.Donef(format, v...)
+		return
+	}
+	l.event("info", "message", fmt.Sprintf(format, v...), nil)
+}
+
+// Warnf ...
+func (l *stepLogger) Warnf(format string, v ...interface{}) {
+	if !l.json {
+		log.Warnf(format, v...)
+		return
+	}
+	l.event("warn", "message", fmt.Sprintf(format, v...), nil)
+}
+
+// Debugf prints only in text format if the go-utils debug log is enabled,
+// debug messages are not part of the JSON event stream.
+func (l *stepLogger) Debugf(format string, v ...interface{}) {
+	if !l.json {
+		log.Debugf(format, v...)
+	}
+}
+
+// Errorf ...
+func (l *stepLogger) Errorf(format string, v ...interface{}) {
+	if !l.json {
+		log.Errorf(format, v...)
+		return
+	}
+	l.event("error", "message", fmt.Sprintf(format, v...), nil)
+}
+
+// ConfigGroup starts a new group of config values.
+func (l *stepLogger) ConfigGroup(title string) {
+	l.configGroup = title
+	if !l.json {
+		log.Infof("%s:", title)
+	}
+}
+
+// Config prints a config value of the current config group.
+func (l *stepLogger) Config(name, value string) {
+	if !l.json {
+		log.Printf("- %s: %s", name, value)
+		return
+	}
+	l.event("info", "config", "", map[string]interface{}{
+		"group": l.configGroup,
+		"name":  name,
+		"value": value,
+	})
+}
+
+// Warnings prints the warnings of a builder method under the given title.
+func (l *stepLogger) Warnings(title string, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	if !l.json {
+		log.Warnf("%s:", title)
+		for _, warning := range warnings {
+			log.Warnf("%s", warning)
+		}
+		return
+	}
+	for _, warning := range warnings {
+		l.event("warn", "warning", warning, map[string]interface{}{
+			"source": title,
+		})
+	}
+}
+
+// SkippedProject prints a project not archived, with the reason,
+// and with the project analysis diagnostics if the project is skipped because of a problem.
+func (l *stepLogger) SkippedProject(skipped builder.SkippedProject) {
+	if !l.json {
+		if !skipped.Warn {
+			log.Printf("Project (%s) skipped: %s", skipped.Project.Name, skipped.Reason)
+			return
+		}
+
+		log.Warnf("Project (%s) skipped: %s", skipped.Project.Name, skipped.Reason)
+		for _, diagnostic := range skipped.Project.Diagnostics {
+			log.Printf("- %s", diagnostic)
+		}
+		return
+	}
+
+	diagnostics := []map[string]interface{}{}
+	for _, diagnostic := range skipped.Project.Diagnostics {
+		diagnostics = append(diagnostics, map[string]interface{}{
+			"severity": diagnostic.Severity,
+			"project":  diagnostic.Project,
+			"field":    diagnostic.Field,
+			"message":  diagnostic.Message,
+		})
+	}
+	level := "info"
+	if skipped.Warn {
+		level = "warn"
+	}
+	l.event(level, "project_skipped", skipped.Reason, map[string]interface{}{
+		"project":     skipped.Project.Name,
+		"path":        skipped.Project.Pth,
+		"host_app":    skipped.HostApp,
+		"diagnostics": diagnostics,
+	})
+}
+
+// Command prints a non build command (clean, restore) before it runs.
+func (l *stepLogger) Command(title, commandStr string) {
+	if !l.json {
+		fmt.Println()
+		log.Infof("%s", title)
+		log.Donef("$ %s", commandStr)
+		fmt.Println()
+		return
+	}
+	l.event("info", "command", title, map[string]interface{}{
+		"command": commandStr,
+	})
+}
+
+// BuildStarted prints a build command before it runs,
+// projectName is empty for the solution level commands.
+func (l *stepLogger) BuildStarted(solutionName, projectName, sdk, commandStr string, alreadyPerformed bool) {
+	if !l.json {
+		fmt.Println()
+		if projectName == "" {
+			log.Infof("Building solution: %s", solutionName)
+		} else {
+			log.Infof("Building project: %s", projectName)
+		}
+		log.Donef("$ %s", commandStr)
+		if alreadyPerformed {
+			log.Warnf("build command already performed, skipping...")
+		}
+		fmt.Println()
+		return
+	}
+	l.event("info", "build_started", "", map[string]interface{}{
+		"solution":          solutionName,
+		"project":           projectName,
+		"sdk":               sdk,
+		"command":           commandStr,
+		"already_performed": alreadyPerformed,
+	})
+}
+
+// BuildFinished prints the result of a builder method, the text format reports failures by the Step's error message.
+func (l *stepLogger) BuildFinished(name string, duration time.Duration, err error) {
+	if !l.json {
+		return
+	}
+
+	level := "info"
+	data := map[string]interface{}{
+		"name":             name,
+		"duration_seconds": duration.Seconds(),
+		"success":          err == nil,
+	}
+	if err != nil {
+		level = "error"
+		data["error"] = err.Error()
+	}
+	l.event(level, "build_finished", "", data)
+}
+
+// ArtifactExported prints the path of an exported artifact and the Environment Variable holding it.
+func (l *stepLogger) ArtifactExported(projectName, artifact, envKey, pth string) {
+	if !l.json {
+		fmt.Println()
+		log.Printf("The %s path is now available in the Environment Variable: %s\nvalue: %s", artifact, envKey, pth)
+		return
+	}
+	l.event("info", "artifact_exported", "", map[string]interface{}{
+		"project":  projectName,
+		"artifact": artifact,
+		"env_key":  envKey,
+		"path":     pth,
+	})
+}
+
+// BuildErrorCategory prints the category of a failed build with the matching log lines and the hints to fix it.
+func (l *stepLogger) BuildErrorCategory(category buildErrorCategory, matchingLines []string) {
+	if !l.json {
+		printBuildErrorCategory(category, matchingLines)
+		return
+	}
+	l.event("error", "build_error_category", category.Description, map[string]interface{}{
+		"category":       category.ID,
+		"matching_lines": matchingLines,
+		"hints":          category.Hints,
+	})
+}
+
+// OutputWriter returns the writer of the build commands' output:
+// in json format each output line becomes a build_output event.
+func (l *stepLogger) OutputWriter() io.Writer {
+	if !l.json {
+		return os.Stdout
+	}
+	return &buildOutputEventWriter{logger: l}
+}
+
+// buildOutputEventWriter splits the build output into lines and prints them as events
+// tagged with the name of the project being built.
+type buildOutputEventWriter struct {
+	logger *stepLogger
+
+	mu          sync.Mutex
+	projectName string
+	buf         []byte
+}
+
+// Write ...
+func (w *buildOutputEventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.printLine(string(bytes.TrimRight(w.buf[:i], "\r")))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// startProject prints the pending partial line and tags the upcoming lines with the given project.
+func (w *buildOutputEventWriter) startProject(projectName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+	w.projectName = projectName
+}
+
+func (w *buildOutputEventWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+}
+
+func (w *buildOutputEventWriter) flush() {
+	if len(w.buf) > 0 {
+		w.printLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *buildOutputEventWriter) printLine(line string) {
+	w.logger.event("info", "build_output", line, map[string]interface{}{
+		"project": w.projectName,
+	})
+}
@@ -1,20 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bitrise-io/go-steputils/input"
 	steputiltools "github.com/bitrise-io/go-steputils/tools"
 	"github.com/bitrise-io/go-utils/command"
-	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-xamarin/builder"
-	"github.com/bitrise-io/go-xamarin/constants"
-	"github.com/bitrise-io/go-xamarin/tools"
-	"github.com/bitrise-io/go-xamarin/tools/buildtools"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools"
 	"github.com/kballard/go-shellquote"
 )
 
@@ -24,14 +33,39 @@ type ConfigsModel struct {
 	XamarinConfiguration string
 	XamarinPlatform      string
 	ProjectTypeWhitelist string
-
-	AndroidCustomOptions string
-	IOSCustomOptions     string
-	TvOSCustomOptions    string
-	MacOSCustomOptions   string
-	BuildTool            string
-
-	DeployDir string
+	BuildCommandTimeout  string
+	BuildTimeout         string
+	BuildLogTailLines    string
+	LogFormat            string
+
+	RunNunitTests      string
+	ExportUITestBundle string
+
+	CleanBuild         string
+	CleanXcodeArchives string
+	XCArchiveDir       string
+
+	AndroidPackageFormat string
+	AndroidUniversalAPK  string
+
+	IOSDistributionMethod   string
+	IOSCodesignKey          string
+	IOSProvisioningProfile  string
+	IOSCodesignEntitlements string
+
+	NugetRestore       string
+	NugetPackageSource string
+	NugetConfigPath    string
+
+	AndroidCustomOptions     string
+	IOSCustomOptions         string
+	TvOSCustomOptions        string
+	MacOSCustomOptions       string
+	MacCatalystCustomOptions string
+	BuildTool                string
+
+	DeployDir     string
+	TestDeployDir string
 }
 
 func createConfigsModelFromEnvs() ConfigsModel {
@@ -40,36 +74,88 @@ func createConfigsModelFromEnvs() ConfigsModel {
 		XamarinConfiguration: os.Getenv("xamarin_configuration"),
 		XamarinPlatform:      os.Getenv("xamarin_platform"),
 		ProjectTypeWhitelist: os.Getenv("project_type_whitelist"),
-
-		AndroidCustomOptions: os.Getenv("android_build_command_custom_options"),
-		IOSCustomOptions:     os.Getenv("ios_build_command_custom_options"),
-		TvOSCustomOptions:    os.Getenv("tvos_build_command_custom_options"),
-		MacOSCustomOptions:   os.Getenv("macos_build_command_custom_options"),
-		BuildTool:            os.Getenv("build_tool"),
-
-		DeployDir: os.Getenv("BITRISE_DEPLOY_DIR"),
+		BuildCommandTimeout:  os.Getenv("build_command_timeout"),
+		BuildTimeout:         os.Getenv("build_timeout"),
+		BuildLogTailLines:    os.Getenv("build_log_tail_lines"),
+		LogFormat:            os.Getenv("log_format"),
+
+		RunNunitTests:      os.Getenv("run_nunit_tests"),
+		ExportUITestBundle: os.Getenv("export_uitest_bundle"),
+
+		CleanBuild:         os.Getenv("clean_build"),
+		CleanXcodeArchives: os.Getenv("clean_xcode_archives"),
+		XCArchiveDir:       os.Getenv("xcarchive_dir"),
+
+		AndroidPackageFormat: os.Getenv("android_package_format"),
+		AndroidUniversalAPK:  os.Getenv("android_universal_apk"),
+
+		IOSDistributionMethod:   os.Getenv("ios_distribution_method"),
+		IOSCodesignKey:          os.Getenv("ios_codesign_key"),
+		IOSProvisioningProfile:  os.Getenv("ios_provisioning_profile"),
+		IOSCodesignEntitlements: os.Getenv("ios_codesign_entitlements"),
+
+		NugetRestore:       os.Getenv("nuget_restore"),
+		NugetPackageSource: os.Getenv("nuget_package_source"),
+		NugetConfigPath:    os.Getenv("nuget_config_path"),
+
+		AndroidCustomOptions:     os.Getenv("android_build_command_custom_options"),
+		IOSCustomOptions:         os.Getenv("ios_build_command_custom_options"),
+		TvOSCustomOptions:        os.Getenv("tvos_build_command_custom_options"),
+		MacOSCustomOptions:       os.Getenv("macos_build_command_custom_options"),
+		MacCatalystCustomOptions: os.Getenv("maccatalyst_build_command_custom_options"),
+		BuildTool:                os.Getenv("build_tool"),
+
+		DeployDir:     os.Getenv("BITRISE_DEPLOY_DIR"),
+		TestDeployDir: os.Getenv("BITRISE_TEST_DEPLOY_DIR"),
 	}
 }
 
 func (configs ConfigsModel) print() {
-	log.Infof("Configs:")
-
-	log.Printf("- XamarinSolution: %s", configs.XamarinSolution)
-	log.Printf("- XamarinConfiguration: %s", configs.XamarinConfiguration)
-	log.Printf("- XamarinPlatform: %s", configs.XamarinPlatform)
-	log.Printf("- ProjectTypeWhitelist: %s", configs.ProjectTypeWhitelist)
-
-	log.Infof("Experimental Configs:")
-
-	log.Printf("- AndroidCustomOptions: %s", configs.AndroidCustomOptions)
-	log.Printf("- IOSCustomOptions: %s", configs.IOSCustomOptions)
-	log.Printf("- TvOSCustomOptions: %s", configs.TvOSCustomOptions)
-	log.Printf("- MacOSCustomOptions: %s", configs.MacOSCustomOptions)
-	log.Printf("- BuildTool: %s", configs.BuildTool)
-
-	log.Infof("Other Configs:")
-
-	log.Printf("- DeployDir: %s", configs.DeployDir)
+	logger.ConfigGroup("Configs")
+
+	logger.Config("XamarinSolution", configs.XamarinSolution)
+	logger.Config("XamarinConfiguration", configs.XamarinConfiguration)
+	logger.Config("XamarinPlatform", configs.XamarinPlatform)
+	logger.Config("ProjectTypeWhitelist", configs.ProjectTypeWhitelist)
+	logger.Config("BuildCommandTimeout", configs.BuildCommandTimeout)
+	logger.Config("BuildTimeout", configs.BuildTimeout)
+	logger.Config("BuildLogTailLines", configs.BuildLogTailLines)
+	logger.Config("LogFormat", configs.LogFormat)
+
+	logger.Config("RunNunitTests", configs.RunNunitTests)
+	logger.Config("ExportUITestBundle", configs.ExportUITestBundle)
+	logger.Config("CleanBuild", configs.CleanBuild)
+	logger.Config("CleanXcodeArchives", configs.CleanXcodeArchives)
+	logger.Config("XCArchiveDir", configs.XCArchiveDir)
+	logger.Config("AndroidPackageFormat", configs.AndroidPackageFormat)
+	logger.Config("AndroidUniversalAPK", configs.AndroidUniversalAPK)
+
+	logger.ConfigGroup("Code Signing Configs")
+
+	logger.Config("IOSDistributionMethod", configs.IOSDistributionMethod)
+	logger.Config("IOSCodesignKey", configs.IOSCodesignKey)
+	logger.Config("IOSProvisioningProfile", configs.IOSProvisioningProfile)
+	logger.Config("IOSCodesignEntitlements", configs.IOSCodesignEntitlements)
+
+	logger.ConfigGroup("NuGet Configs")
+
+	logger.Config("NugetRestore", configs.NugetRestore)
+	logger.Config("NugetPackageSource", configs.NugetPackageSource)
+	logger.Config("NugetConfigPath", configs.NugetConfigPath)
+
+	logger.ConfigGroup("Experimental Configs")
+
+	logger.Config("AndroidCustomOptions", configs.AndroidCustomOptions)
+	logger.Config("IOSCustomOptions", configs.IOSCustomOptions)
+	logger.Config("TvOSCustomOptions", configs.TvOSCustomOptions)
+	logger.Config("MacOSCustomOptions", configs.MacOSCustomOptions)
+	logger.Config("MacCatalystCustomOptions", configs.MacCatalystCustomOptions)
+	logger.Config("BuildTool", configs.BuildTool)
+
+	logger.ConfigGroup("Other Configs")
+
+	logger.Config("DeployDir", configs.DeployDir)
+	logger.Config("TestDeployDir", configs.TestDeployDir)
 }
 
 func (configs ConfigsModel) validate() error {
@@ -89,13 +175,105 @@ func (configs ConfigsModel) validate() error {
 		return fmt.Errorf("BuildTool - %s", err)
 	}
 
+	if _, err := parseTimeout(configs.BuildCommandTimeout); err != nil {
+		return fmt.Errorf("BuildCommandTimeout - %s", err)
+	}
+
+	if _, err := parseTimeout(configs.BuildTimeout); err != nil {
+		return fmt.Errorf("BuildTimeout - %s", err)
+	}
+
+	if lines, err := strconv.Atoi(configs.BuildLogTailLines); err != nil || lines < 0 {
+		return fmt.Errorf("BuildLogTailLines - invalid number of lines: %s", configs.BuildLogTailLines)
+	}
+
+	if err := input.ValidateWithOptions(configs.LogFormat, logFormatText, logFormatJSON); err != nil {
+		return fmt.Errorf("LogFormat - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.RunNunitTests, "yes", "no"); err != nil {
+		return fmt.Errorf("RunNunitTests - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.ExportUITestBundle, "yes", "no"); err != nil {
+		return fmt.Errorf("ExportUITestBundle - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.CleanBuild, "no", "delete", "msbuild"); err != nil {
+		return fmt.Errorf("CleanBuild - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.CleanXcodeArchives, "yes", "no"); err != nil {
+		return fmt.Errorf("CleanXcodeArchives - %s", err)
+	}
+
+	if _, err := constants.ParseAndroidPackageFormat(configs.AndroidPackageFormat); err != nil {
+		return fmt.Errorf("AndroidPackageFormat - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.AndroidUniversalAPK, "yes", "no"); err != nil {
+		return fmt.Errorf("AndroidUniversalAPK - %s", err)
+	}
+
+	if err := input.ValidateWithOptions(configs.IOSDistributionMethod, distributionMethods...); err != nil {
+		return fmt.Errorf("IOSDistributionMethod - %s", err)
+	}
+
+	if configs.IOSCodesignEntitlements != "" {
+		if err := input.ValidateIfPathExists(configs.IOSCodesignEntitlements); err != nil {
+			return fmt.Errorf("IOSCodesignEntitlements - %s", err)
+		}
+	}
+
+	if err := input.ValidateWithOptions(configs.NugetRestore, "yes", "no"); err != nil {
+		return fmt.Errorf("NugetRestore - %s", err)
+	}
+
+	if configs.NugetConfigPath != "" {
+		if err := input.ValidateIfPathExists(configs.NugetConfigPath); err != nil {
+			return fmt.Errorf("NugetConfigPath - %s", err)
+		}
+	}
+
 	return nil
 }
 
+// timeoutInputs are the inputs setting the build time limits.
+var timeoutInputs = map[tools.TimeoutLimit]string{
+	tools.CommandTimeout: "build_command_timeout",
+	tools.OverallTimeout: "build_timeout",
+}
+
+// interrupted tells if the build commands were stopped by a signal, not by the build timeout.
+func interrupted(ctx context.Context) bool {
+	return ctx.Err() == context.Canceled
+}
+
+// parseTimeout parses a timeout given in seconds, empty or 0 means no timeout.
+func parseTimeout(seconds string) (time.Duration, error) {
+	if seconds == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(seconds)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %s, should be a number of seconds", seconds)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("invalid timeout: %s, should not be negative", seconds)
+	}
+
+	return time.Duration(value) * time.Second, nil
+}
+
 func exportZippedArtifactDir(pth, deployDir, envKey string) (string, error) {
+	return exportZippedArtifactDirWithName(pth, deployDir, filepath.Base(pth)+".zip", envKey)
+}
+
+func exportZippedArtifactDirWithName(pth, deployDir, zipName, envKey string) (string, error) {
 	parentDir := filepath.Dir(pth)
 	dirName := filepath.Base(pth)
-	deployPth := filepath.Join(deployDir, dirName+".zip")
+	deployPth := filepath.Join(deployDir, zipName)
 	cmd := command.New("/usr/bin/zip", "-rTy", deployPth, dirName)
 	cmd.SetDir(parentDir)
 	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
@@ -110,6 +288,22 @@ func exportZippedArtifactDir(pth, deployDir, envKey string) (string, error) {
 	return deployPth, nil
 }
 
+func exportZippedArtifactDirContent(pth, deployDir, zipName, envKey string) (string, error) {
+	deployPth := filepath.Join(deployDir, zipName)
+	cmd := command.New("/usr/bin/zip", "-rTy", deployPth, ".")
+	cmd.SetDir(pth)
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to zip dir: %s, output: %s, error: %s", pth, out, err)
+	}
+
+	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, deployPth); err != nil {
+		return "", fmt.Errorf("failed to export artifact path (%s) into (%s)", deployPth, envKey)
+	}
+
+	return deployPth, nil
+}
+
 func exportArtifactDir(pth, deployDir, envKey string) (string, error) {
 	base := filepath.Base(pth)
 	deployPth := filepath.Join(deployDir, base)
@@ -126,11 +320,13 @@ func exportArtifactDir(pth, deployDir, envKey string) (string, error) {
 }
 
 func exportArtifactFile(pth, deployDir, envKey string) (string, error) {
-	base := filepath.Base(pth)
-	deployPth := filepath.Join(deployDir, base)
+	return exportArtifactFileWithName(pth, deployDir, filepath.Base(pth), envKey)
+}
 
-	if err := command.CopyFile(pth, deployPth); err != nil {
-		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployPth)
+func exportArtifactFileWithName(pth, deployDir, name, envKey string) (string, error) {
+	deployPth, err := copyArtifactFile(pth, deployDir, name)
+	if err != nil {
+		return "", err
 	}
 
 	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, deployPth); err != nil {
@@ -140,19 +336,374 @@ func exportArtifactFile(pth, deployDir, envKey string) (string, error) {
 	return deployPth, nil
 }
 
+// copyArtifactFile copies the artifact into the deploy dir under the given name, without exporting its path.
+func copyArtifactFile(pth, deployDir, name string) (string, error) {
+	deployPth := filepath.Join(deployDir, name)
+
+	if err := command.CopyFile(pth, deployPth); err != nil {
+		return "", fmt.Errorf("failed to move artifact (%s) to (%s)", pth, deployPth)
+	}
+
+	return deployPth, nil
+}
+
+// mainAPKIndex returns the index of the project's apk exported as BITRISE_APK_PATH: the universal apk,
+// or the first per-ABI apk if the project builds per-ABI apks only, -1 if the project has no apk.
+func mainAPKIndex(outputs []builder.OutputModel) int {
+	index := -1
+	for i, output := range outputs {
+		if output.OutputType != constants.OutputTypeAPK {
+			continue
+		}
+		if output.ABI == "" {
+			return i
+		}
+		if index == -1 {
+			index = i
+		}
+	}
+	return index
+}
+
+// apkDeployName returns the file name of the exported apk, the per-ABI apks' names contain their ABI.
+func apkDeployName(output builder.OutputModel) string {
+	name := filepath.Base(output.Pth)
+	if output.ABI == "" || strings.Contains(strings.ToLower(name), strings.ToLower(output.ABI)) {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + output.ABI + ext
+}
+
+// addUniversalAPKs generates a universal APK for the Android projects which built an app bundle, but no APK.
+func addUniversalAPKs(output builder.ProjectOutputMap) {
+	for projectName, projectOutput := range output {
+		if projectOutput.ProjectType != constants.SDKAndroid {
+			continue
+		}
+
+		aabPth := ""
+		hasAPK := false
+		for _, output := range projectOutput.Outputs {
+			switch output.OutputType {
+			case constants.OutputTypeAAB:
+				aabPth = output.Pth
+			case constants.OutputTypeAPK:
+				hasAPK = true
+			}
+		}
+		if aabPth == "" || hasAPK {
+			continue
+		}
+
+		tmpDir, err := pathutil.NormalizedOSTempDirPath("universal-apk")
+		if err != nil {
+			logger.Warnf("Failed to create tmp dir for the universal apk, error: %s", err)
+			continue
+		}
+
+		apkPth, err := generateUniversalAPK(aabPth, tmpDir)
+		if err != nil {
+			logger.Warnf("Failed to generate universal apk for project (%s), error: %s", projectName, err)
+			continue
+		}
+
+		projectOutput.Outputs = append(projectOutput.Outputs, builder.OutputModel{
+			Pth:        apkPth,
+			OutputType: constants.OutputTypeAPK,
+		})
+		output[projectName] = projectOutput
+	}
+}
+
+// resolveCodesigning validates the iOS code signing inputs against the installed provisioning profiles
+// and returns the code signing properties of the iOS and tvOS builds.
+func resolveCodesigning(configs ConfigsModel) builder.CodesignSettings {
+	logger.Println()
+	logger.Infof("Resolving iOS code signing settings")
+
+	profilesDir, err := provisioningProfilesDir()
+	if err != nil {
+		failf("Failed to get provisioning profiles dir, error: %s", err)
+	}
+
+	profiles, warnings, err := listProvisioningProfiles(profilesDir)
+	logger.Warnings("Provisioning profile warnings", warnings)
+	if err != nil {
+		failf("Failed to list provisioning profiles, error: %s", err)
+	}
+	logger.Printf("%d provisioning profiles installed in: %s", len(profiles), profilesDir)
+
+	settings, profile, err := resolveCodesignSettings(configs.IOSDistributionMethod, configs.IOSCodesignKey, configs.IOSProvisioningProfile, configs.IOSCodesignEntitlements, profiles, time.Now())
+	if err != nil {
+		failf("Invalid code signing settings, error: %s", err)
+	}
+
+	if profile != nil {
+		logger.Printf("Provisioning profile: %s, team: %s, expires: %s", profile, profile.TeamName, profile.ExpirationDate.Format(time.RFC3339))
+	}
+	logger.Printf("CodesignKey: %s", settings.Key)
+	logger.Printf("CodesignProvision: %s", settings.Provision)
+	logger.Printf("CodesignEntitlements: %s", settings.EntitlementsPth)
+
+	return settings
+}
+
+// clean removes the previous build's outputs, so that only the artifacts of this build are collected.
+func clean(ctx context.Context, b builder.Model, configs ConfigsModel) {
+	clearCallback := func(proj project.Model, dir string) {
+		logger.Printf("- %s: removing %s", proj.Name, dir)
+	}
+
+	switch configs.CleanBuild {
+	case "delete":
+		logger.Println()
+		logger.Infof("Removing bin and obj directories")
+
+		if err := b.CleanAll(clearCallback); err != nil {
+			failf("Failed to clean projects, error: %s", err)
+		}
+	case "msbuild":
+		callback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
+			logger.Command("Cleaning solution: "+solutionName, commandStr)
+		}
+
+		if err := b.CleanSolutionWithContext(ctx, configs.XamarinConfiguration, configs.XamarinPlatform, callback); err != nil {
+			if interrupted(ctx) {
+				failf("Clean interrupted, child processes were stopped")
+			}
+			reportTimeout(err)
+			failf("Failed to clean solution, error: %s", err)
+		}
+	}
+
+	if configs.CleanXcodeArchives == "yes" {
+		logger.Println()
+		logger.Infof("Removing previous xcarchives of the projects")
+
+		if err := b.CleanXcodeArchives(clearCallback); err != nil {
+			failf("Failed to remove previous xcarchives, error: %s", err)
+		}
+	}
+}
+
+// runNunitTests builds the solution and runs its NUnit test projects,
+// exports the results as Bitrise test reports and fails if any test fails.
+func runNunitTests(ctx context.Context, b builder.Model, configs ConfigsModel, callback builder.BuildCommandCallback) {
+	logger.Println()
+	logger.Infof("Running NUnit tests")
+
+	resultDir, err := pathutil.NormalizedOSTempDirPath("nunit-results")
+	if err != nil {
+		failf("Failed to create test result dir, error: %s", err)
+	}
+	b.SetTestResultDir(resultDir)
+
+	startTime := time.Now()
+
+	warnings, testErr := b.BuildAndRunAllNunitTestProjectsWithContext(ctx, configs.XamarinConfiguration, configs.XamarinPlatform, callback, nil)
+	logger.BuildFinished("nunit_tests", time.Since(startTime), testErr)
+	logger.Warnings("Test warnings", warnings)
+	if interrupted(ctx) {
+		failf("NUnit tests interrupted, child processes were stopped")
+	}
+
+	resultPths, err := filepath.Glob(filepath.Join(resultDir, "*.xml"))
+	if err != nil {
+		failf("Failed to search for test results, error: %s", err)
+	}
+
+	failedTests := 0
+	if configs.TestDeployDir == "" {
+		logger.Warnf("BITRISE_TEST_DEPLOY_DIR is not set, test results are not exported")
+	} else {
+		logger.Println()
+		for _, resultPth := range resultPths {
+			testName := strings.TrimSuffix(filepath.Base(resultPth), filepath.Ext(resultPth))
+
+			tests, failures, err := exportNunitResultAsTestReport(resultPth, configs.TestDeployDir, testName)
+			if err != nil {
+				logger.Warnf("Failed to export test result of %s, error: %s", testName, err)
+				continue
+			}
+			failedTests += failures
+
+			logger.Printf("%s: %d tests, %d failed", testName, tests, failures)
+		}
+	}
+
+	if testErr != nil {
+		reportTimeout(testErr)
+		failf("NUnit tests failed, error: %s", testErr)
+	}
+	if failedTests > 0 {
+		failf("%d NUnit tests failed", failedTests)
+	}
+
+	logger.Donef("NUnit tests passed")
+}
+
+// buildCommandCallback announces the build commands and writes their output into the build log of their project.
+func buildCommandCallback(logWriter *projectLogWriter) builder.BuildCommandCallback {
+	return func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
+		logger.BuildStarted(solutionName, projectName, string(sdk), commandStr, alreadyPerformed)
+		if alreadyPerformed {
+			// the shared solution level command is not run again, its output is in the log of the project it ran for
+			return
+		}
+		if projectName == "" {
+			// solution level command
+			projectName = solutionName
+		}
+
+		if err := logWriter.startProject(projectName, commandStr); err != nil {
+			logger.Warnf("Failed to start build log of project (%s), error: %s", projectName, err)
+		}
+	}
+}
+
+// exportUITestBundles builds the Xamarin.UITest projects referring to the archived projects
+// and exports their zipped output directories.
+func exportUITestBundles(ctx context.Context, b builder.Model, configs ConfigsModel, prepareCallback builder.PrepareCommandCallback, buildOutput io.Writer, tailLines int) {
+	logger.Println()
+	logger.Infof("Building Xamarin.UITest projects")
+
+	// the build's log writer is closed by now, the test projects get their own
+	logWriter := newProjectLogWriter(buildOutput, configs.DeployDir, tailLines)
+	b.SetOutputs(logWriter, logWriter)
+
+	startTime := time.Now()
+
+	// the tests are not run here, the exported bundles are run on devices together with the archived apps
+	warnings, err := b.BuildAllXamarinUITestProjectsWithContext(ctx, configs.XamarinConfiguration, configs.XamarinPlatform, prepareCallback, buildCommandCallback(logWriter))
+	if err := logWriter.close(); err != nil {
+		logger.Warnf("Failed to close build log, error: %s", err)
+	}
+	logger.BuildFinished("xamarin_uitest_build", time.Since(startTime), err)
+	logger.Warnings("Build warnings", warnings)
+	if err != nil {
+		if interrupted(ctx) {
+			failf("Xamarin.UITest build interrupted, child build processes were stopped")
+		}
+		reportTimeout(err)
+		failf("Xamarin.UITest build failed, error: %s", err)
+	}
+
+	endTime := time.Now()
+
+	testOutput, warnings, err := b.CollectXamarinUITestProjectOutputs(configs.XamarinConfiguration, configs.XamarinPlatform, startTime, endTime)
+	logger.Warnings("Output warnings", warnings)
+	if err != nil {
+		failf("Failed to collect Xamarin.UITest output, error: %s", err)
+	}
+
+	if len(testOutput) == 0 {
+		failf("No Xamarin.UITest output generated")
+	}
+
+	testProjectNames := make([]string, 0, len(testOutput))
+	for testProjectName := range testOutput {
+		testProjectNames = append(testProjectNames, testProjectName)
+	}
+	sort.Strings(testProjectNames)
+
+	bundlePths := []string{}
+	for _, testProjectName := range testProjectNames {
+		testProjectOutput := testOutput[testProjectName]
+		logger.Println()
+		logger.Donef("%s test assembly: %s", testProjectName, testProjectOutput.Output.Pth)
+		logger.Printf("Referred projects: %s", strings.Join(testProjectOutput.ReferredProjectNames, ", "))
+
+		envKey := "BITRISE_XAMARIN_UITEST_BUNDLE_PATH"
+		testAssemblyDir := filepath.Dir(testProjectOutput.Output.Pth)
+		pth, err := exportZippedArtifactDirWithName(testAssemblyDir, configs.DeployDir, testProjectName+".zip", envKey)
+		if err != nil {
+			failf("Failed to export Xamarin.UITest bundle, error: %s", err)
+		}
+		logger.ArtifactExported(testProjectName, "Xamarin.UITest bundle zip", envKey, pth)
+		bundlePths = append(bundlePths, pth)
+	}
+
+	// BITRISE_XAMARIN_UITEST_BUNDLE_PATH holds the last bundle only, if there are more test projects
+	envKey := "BITRISE_XAMARIN_UITEST_BUNDLE_PATH_LIST"
+	value := strings.Join(bundlePths, "|")
+	if err := steputiltools.ExportEnvironmentWithEnvman(envKey, value); err != nil {
+		failf("Failed to export Xamarin.UITest bundle path list into (%s), error: %s", envKey, err)
+	}
+	logger.ArtifactExported("", "Xamarin.UITest bundle list", envKey, value)
+}
+
+// restoreNugetPackages restores the solution's NuGet packages if any of the buildable projects misses them.
+func restoreNugetPackages(ctx context.Context, b builder.Model, configs ConfigsModel) {
+	logger.Println()
+	logger.Infof("Checking NuGet packages restore state")
+
+	projects, err := b.ProjectsMissingRestore(configs.NugetConfigPath)
+	if err != nil {
+		failf("Failed to check NuGet packages restore state, error: %s", err)
+	}
+
+	if len(projects) == 0 {
+		logger.Donef("NuGet packages are restored")
+		return
+	}
+
+	logger.Warnf("NuGet packages are not restored for projects:")
+	for _, proj := range projects {
+		logger.Warnf("- %s", proj.Name)
+	}
+
+	callback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
+		logger.Command("Restoring NuGet packages of solution: "+solutionName, commandStr)
+	}
+
+	if err := b.RestoreNugetPackagesWithContext(ctx, projects, configs.NugetPackageSource, configs.NugetConfigPath, callback); err != nil {
+		if interrupted(ctx) {
+			failf("NuGet restore interrupted, child processes were stopped")
+		}
+		reportTimeout(err)
+		failf("NuGet restore failed, error: %s", err)
+	}
+}
+
+// reportTimeout prints the time limit, the command and the last output lines of a timed out command,
+// it returns false if the error is not a timeout.
+func reportTimeout(err error) bool {
+	var timeoutErr *tools.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		return false
+	}
+
+	logger.Errorf("Project (%s) timed out after %s (%s)", timeoutErr.ProjectName, timeoutErr.Timeout, timeoutInputs[timeoutErr.Limit])
+	logger.Errorf("$ %s", timeoutErr.Command)
+
+	if len(timeoutErr.LastLines) > 0 {
+		logger.Warnf("Last %d lines of the command output:", len(timeoutErr.LastLines))
+		for _, line := range timeoutErr.LastLines {
+			logger.Printf("%s", line)
+		}
+		logger.Println()
+	}
+
+	return true
+}
+
 func failf(format string, v ...interface{}) {
-	log.Errorf(format, v...)
+	logger.Errorf(format, v...)
 	os.Exit(1)
 }
 
 func main() {
 	configs := createConfigsModelFromEnvs()
+	logger = newStepLogger(configs.LogFormat)
+	logging.SetLogger(logger)
 
-	fmt.Println()
+	logger.Println()
 	configs.print()
 
 	if err := configs.validate(); err != nil {
-		fmt.Println()
+		logger.Println()
 		failf("Issue with input: %s", err)
 	}
 
@@ -180,10 +731,11 @@ func main() {
 	// prepare custom options
 	projectTypeCustomOptions := map[constants.SDK][]string{}
 	projectTypeRawCustomOptions := map[constants.SDK]string{
-		constants.SDKAndroid: configs.AndroidCustomOptions,
-		constants.SDKIOS:     configs.IOSCustomOptions,
-		constants.SDKTvOS:    configs.TvOSCustomOptions,
-		constants.SDKMacOS:   configs.MacOSCustomOptions,
+		constants.SDKAndroid:     configs.AndroidCustomOptions,
+		constants.SDKIOS:         configs.IOSCustomOptions,
+		constants.SDKTvOS:        configs.TvOSCustomOptions,
+		constants.SDKMacOS:       configs.MacOSCustomOptions,
+		constants.SDKMacCatalyst: configs.MacCatalystCustomOptions,
 	}
 	for projectType, rawOptions := range projectTypeRawCustomOptions {
 		if rawOptions == "" {
@@ -192,7 +744,7 @@ func main() {
 
 		split, err := shellquote.Split(rawOptions)
 		if err != nil {
-			log.Errorf("failed to split options (%s), error: %s", rawOptions, err)
+			logger.Errorf("failed to split options (%s), error: %s", rawOptions, err)
 		}
 		projectTypeCustomOptions[projectType] = split
 	}
@@ -200,8 +752,8 @@ func main() {
 
 	//
 	// build
-	fmt.Println()
-	log.Infof("Building all projects in solution: %s", configs.XamarinSolution)
+	logger.Println()
+	logger.Infof("Building all projects in solution: %s", configs.XamarinSolution)
 
 	buildTool := buildtools.Msbuild
 	if configs.BuildTool == "xbuild" {
@@ -213,6 +765,75 @@ func main() {
 		failf("Failed to create xamarin builder, error: %s", err)
 	}
 
+	logger.Println()
+	logger.Infof("Running preflight checks")
+
+	preflightWarnings, err := b.PreflightCheck(configs.XamarinConfiguration, configs.XamarinPlatform)
+	logger.Warnings("Preflight warnings", preflightWarnings)
+
+	// every project not archived is listed with the reason, the ones skipped because of analysis problems,
+	// like a missing ProjectTypeGuids, would silently disappear otherwise
+	if skippedProjects, err := b.SkippedProjects(configs.XamarinConfiguration, configs.XamarinPlatform); err == nil {
+		for _, skipped := range skippedProjects {
+			logger.SkippedProject(skipped)
+		}
+	}
+	if err != nil {
+		failf("Preflight check failed, error: %s", err)
+	}
+	logger.Donef("Preflight checks passed")
+
+	commandTimeout, _ := parseTimeout(configs.BuildCommandTimeout)
+	b.SetCommandTimeout(commandTimeout)
+
+	androidPackageFormat, _ := constants.ParseAndroidPackageFormat(configs.AndroidPackageFormat)
+	b.SetAndroidPackageFormat(androidPackageFormat)
+
+	if configs.IOSDistributionMethod != distributionMethodProject || configs.IOSCodesignKey != "" || configs.IOSProvisioningProfile != "" || configs.IOSCodesignEntitlements != "" {
+		b.SetCodesignSettings(resolveCodesigning(configs))
+	}
+
+	if err := os.MkdirAll(configs.DeployDir, 0755); err != nil {
+		failf("Failed to create deploy dir (%s), error: %s", configs.DeployDir, err)
+	}
+
+	if configs.XCArchiveDir != "" {
+		archiveDir, err := pathutil.AbsPath(configs.XCArchiveDir)
+		if err != nil {
+			failf("Failed to expand xcarchive dir (%s), error: %s", configs.XCArchiveDir, err)
+		}
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			failf("Failed to create xcarchive dir (%s), error: %s", archiveDir, err)
+		}
+		b.SetArchiveDir(archiveDir)
+	}
+
+	buildOutput := logger.OutputWriter()
+	b.SetOutputs(buildOutput, buildOutput)
+
+	// stop the running build commands and their child processes on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// a single deadline for all the build commands, from cleaning to the Xamarin.UITest build
+	if timeout, _ := parseTimeout(configs.BuildTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = tools.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if configs.CleanBuild != "no" || configs.CleanXcodeArchives == "yes" {
+		clean(ctx, b, configs)
+	}
+
+	if configs.NugetRestore == "yes" {
+		restoreNugetPackages(ctx, b, configs)
+	}
+
+	tailLines, _ := strconv.Atoi(configs.BuildLogTailLines)
+	logWriter := newProjectLogWriter(buildOutput, configs.DeployDir, tailLines)
+	b.SetOutputs(logWriter, logWriter)
+
 	prepareCallback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, command *tools.Editable) {
 		options, ok := projectTypeCustomOptions[sdk]
 		if ok {
@@ -220,26 +841,59 @@ func main() {
 		}
 	}
 
-	callback := func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool) {
-		fmt.Println()
-		log.Infof("Building project: %s", projectName)
-		log.Donef("$ %s", commandStr)
-		if alreadyPerformed {
-			log.Warnf("build command already performed, skipping...")
-		}
-		fmt.Println()
+	callback := buildCommandCallback(logWriter)
+
+	if configs.RunNunitTests == "yes" {
+		runNunitTests(ctx, b, configs, callback)
 	}
 
 	startTime := time.Now()
 
-	warnings, err := b.BuildAllProjects(configs.XamarinConfiguration, configs.XamarinPlatform, true, prepareCallback, callback)
-	if len(warnings) > 0 {
-		log.Warnf("Build warnings:")
-		for _, warning := range warnings {
-			log.Warnf(warning)
+	warnings, err := b.BuildAllProjectsWithContext(ctx, configs.XamarinConfiguration, configs.XamarinPlatform, true, prepareCallback, callback)
+	if err := logWriter.close(); err != nil {
+		logger.Warnf("Failed to close build log, error: %s", err)
+	}
+	logger.BuildFinished("build", time.Since(startTime), err)
+	logger.Warnings("Build warnings", warnings)
+	if logPths := logWriter.logPaths(); len(logPths) > 0 {
+		logger.Println()
+		logger.Infof("Build logs:")
+		for _, pth := range logPths {
+			logger.Printf("- %s", pth)
 		}
 	}
 	if err != nil {
+		if interrupted(ctx) {
+			failf("Build interrupted, child build processes were stopped")
+		}
+
+		logger.Println()
+		errorCategory := buildErrorCategoryUnknown
+		if reportTimeout(err) {
+			errorCategory = buildErrorCategoryTimeout
+		} else if projectName, lines := logWriter.lastLines(); len(lines) > 0 {
+			logger.Warnf("Last %d lines of the project (%s) build log:", len(lines), projectName)
+			for _, line := range lines {
+				logger.Printf("%s", line)
+			}
+			logger.Println()
+		}
+
+		if logPth := logWriter.currentLogPath(); logPth != "" && errorCategory == buildErrorCategoryUnknown {
+			category, matchingLines, classifyErr := classifyBuildLog(logPth)
+			if classifyErr != nil {
+				logger.Warnf("Failed to classify build error, error: %s", classifyErr)
+			} else if category != nil {
+				errorCategory = category.ID
+				logger.BuildErrorCategory(*category, matchingLines)
+				logger.Println()
+			}
+		}
+
+		if exportErr := steputiltools.ExportEnvironmentWithEnvman(buildErrorCategoryEnvKey, errorCategory); exportErr != nil {
+			logger.Warnf("Failed to export error category into (%s), error: %s", buildErrorCategoryEnvKey, exportErr)
+		}
+
 		failf("Build failed, error: %s", err)
 	}
 
@@ -253,30 +907,51 @@ func main() {
 	if len(output) == 0 {
 		failf("No output generated")
 	}
+
+	if configs.AndroidUniversalAPK == "yes" {
+		addUniversalAPKs(output)
+	}
 	// ---
 
 	// Export outputs
-	fmt.Println()
-	log.Infof("Exporting generated outputs...")
+	logger.Println()
+	logger.Infof("Exporting generated outputs...")
+
+	var apkPths []string
 
 	for projectName, projectOutput := range output {
 		outputNumber := len(projectOutput.Outputs)
-		fmt.Println()
-		log.Donef("%s outputs (%d):", projectName, outputNumber)
+		logger.Println()
+		logger.Donef("%s outputs (%d):", projectName, outputNumber)
+
+		apkIndex := mainAPKIndex(projectOutput.Outputs)
 
 		for i, output := range projectOutput.Outputs {
-			log.Infof("%d/%d - %s - Type: %s", i+1, outputNumber, output.Pth, projectOutput.ProjectType)
+			logger.Infof("%d/%d - %s - Type: %s", i+1, outputNumber, output.Pth, projectOutput.ProjectType)
 
 			// Android outputs
 			if projectOutput.ProjectType == constants.SDKAndroid {
 				if output.OutputType == constants.OutputTypeAPK {
-					envKey := "BITRISE_APK_PATH"
-					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
-					if err != nil {
-						failf("Failed to export apk, error: %s", err)
+					var pth string
+					if i == apkIndex {
+						envKey := "BITRISE_APK_PATH"
+						exportedPth, err := exportArtifactFileWithName(output.Pth, configs.DeployDir, apkDeployName(output), envKey)
+						if err != nil {
+							failf("Failed to export apk, error: %s", err)
+						}
+						logger.ArtifactExported(projectName, "apk", envKey, exportedPth)
+						pth = exportedPth
+					} else {
+						// the other per-ABI apks are exported in BITRISE_APK_PATH_LIST only
+						copiedPth, err := copyArtifactFile(output.Pth, configs.DeployDir, apkDeployName(output))
+						if err != nil {
+							failf("Failed to export apk, error: %s", err)
+						}
+						logger.Printf("The %s apk is copied to: %s", output.ABI, copiedPth)
+						pth = copiedPth
 					}
-					fmt.Println()
-					log.Printf("The apk path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+
+					apkPths = append(apkPths, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeAAB {
@@ -285,8 +960,32 @@ func main() {
 					if err != nil {
 						failf("Failed to export aab, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The aab path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "aab", envKey, pth)
+				}
+
+				if output.OutputType == constants.OutputTypeMapping {
+					envKey := "BITRISE_MAPPING_PATH"
+					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
+					if err != nil {
+						failf("Failed to export mapping, error: %s", err)
+					}
+					logger.ArtifactExported(projectName, "mapping", envKey, pth)
+				}
+
+				if output.OutputType == constants.OutputTypeNativeSymbols {
+					envKey := "BITRISE_ANDROID_NATIVE_SYMBOLS_PATH"
+					var pth string
+					var err error
+					if filepath.Ext(output.Pth) == ".zip" {
+						pth, err = exportArtifactFile(output.Pth, configs.DeployDir, envKey)
+					} else {
+						// Play Console expects the ABI directories at the root of the zip
+						pth, err = exportZippedArtifactDirContent(output.Pth, configs.DeployDir, "native-debug-symbols.zip", envKey)
+					}
+					if err != nil {
+						failf("Failed to export native symbols, error: %s", err)
+					}
+					logger.ArtifactExported(projectName, "native symbols zip", envKey, pth)
 				}
 			}
 
@@ -298,8 +997,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export xcarchive, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "xcarchive", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeIPA {
@@ -308,8 +1006,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export ipa, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The ipa path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "ipa", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeDSYM {
@@ -318,8 +1015,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export dsym, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The dsym zip path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "dsym zip", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeAPP {
@@ -328,8 +1024,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export app, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "app", envKey, pth)
 				}
 			}
 
@@ -341,8 +1036,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export xcarchive, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "xcarchive", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeIPA {
@@ -351,8 +1045,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export ipa, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The ipa path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "ipa", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeDSYM {
@@ -361,8 +1054,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export dsym, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The dsym zip path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "dsym zip", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeAPP {
@@ -371,8 +1063,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export app, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "app", envKey, pth)
 				}
 			}
 
@@ -384,8 +1075,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export xcarchive, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The xcarchive path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "xcarchive", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypeAPP {
@@ -394,8 +1084,7 @@ func main() {
 					if err != nil {
 						failf("Failed to export app, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The app path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "app", envKey, pth)
 				}
 
 				if output.OutputType == constants.OutputTypePKG {
@@ -404,11 +1093,44 @@ func main() {
 					if err != nil {
 						failf("Failed to export pkg, error: %s", err)
 					}
-					fmt.Println()
-					log.Printf("The pkg path is now available in the Environment Variable: %s\nvalue: %s", envKey, pth)
+					logger.ArtifactExported(projectName, "pkg", envKey, pth)
+				}
+			}
+
+			// Mac Catalyst outputs
+			if projectOutput.ProjectType == constants.SDKMacCatalyst {
+				if output.OutputType == constants.OutputTypeAPP {
+					envKey := "BITRISE_MACCATALYST_APP_PATH"
+					pth, err := exportArtifactDir(output.Pth, configs.DeployDir, envKey)
+					if err != nil {
+						failf("Failed to export app, error: %s", err)
+					}
+					logger.ArtifactExported(projectName, "app", envKey, pth)
+				}
+
+				if output.OutputType == constants.OutputTypePKG {
+					envKey := "BITRISE_MACCATALYST_PKG_PATH"
+					pth, err := exportArtifactFile(output.Pth, configs.DeployDir, envKey)
+					if err != nil {
+						failf("Failed to export pkg, error: %s", err)
+					}
+					logger.ArtifactExported(projectName, "pkg", envKey, pth)
 				}
 			}
 		}
 	}
+
+	if len(apkPths) > 0 {
+		envKey := "BITRISE_APK_PATH_LIST"
+		value := strings.Join(apkPths, "|")
+		if err := steputiltools.ExportEnvironmentWithEnvman(envKey, value); err != nil {
+			failf("Failed to export apk path list into (%s), error: %s", envKey, err)
+		}
+		logger.ArtifactExported("", "apk list", envKey, value)
+	}
 	// ---
+
+	if configs.ExportUITestBundle == "yes" {
+		exportUITestBundles(ctx, b, configs, prepareCallback, buildOutput, tailLines)
+	}
 }
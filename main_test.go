@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/builder"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+)
+
+func TestMainAPKIndex(t *testing.T) {
+	aab := builder.OutputModel{Pth: "app.aab", OutputType: constants.OutputTypeAAB}
+	universal := builder.OutputModel{Pth: "app.apk", OutputType: constants.OutputTypeAPK}
+	arm64 := builder.OutputModel{Pth: "app-arm64-v8a.apk", OutputType: constants.OutputTypeAPK, ABI: "arm64-v8a"}
+	x86 := builder.OutputModel{Pth: "app-x86_64.apk", OutputType: constants.OutputTypeAPK, ABI: "x86_64"}
+
+	tests := []struct {
+		name    string
+		outputs []builder.OutputModel
+		want    int
+	}{
+		{name: "no apk", outputs: []builder.OutputModel{aab}, want: -1},
+		{name: "single apk", outputs: []builder.OutputModel{aab, universal}, want: 1},
+		{name: "per-ABI apks only", outputs: []builder.OutputModel{arm64, x86, aab}, want: 0},
+		{name: "universal apk after the per-ABI apks", outputs: []builder.OutputModel{arm64, x86, universal}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mainAPKIndex(tt.outputs); got != tt.want {
+				t.Errorf("mainAPKIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// universalAPKEntry is the universal APK's name in the .apks archive generated by bundletool.
+const universalAPKEntry = "universal.apk"
+
+// generateUniversalAPK generates a universal APK from the given app bundle into the given directory, using bundletool.
+// The APK is signed with the debug keystore, so it is meant for QA distribution, not for the store.
+func generateUniversalAPK(aabPth, outputDir string) (string, error) {
+	bundletoolPth, err := exec.LookPath("bundletool")
+	if err != nil {
+		return "", fmt.Errorf("bundletool not found in PATH")
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(aabPth), filepath.Ext(aabPth))
+	apksPth := filepath.Join(outputDir, baseName+".apks")
+	apkPth := filepath.Join(outputDir, baseName+"-universal.apk")
+
+	cmd := command.New(bundletoolPth, "build-apks", "--bundle="+aabPth, "--output="+apksPth, "--mode=universal", "--overwrite")
+	logger.Command("Generating universal apk", cmd.PrintableCommandArgs())
+
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return "", fmt.Errorf("bundletool failed, output: %s, error: %s", out, err)
+	}
+
+	if err := extractZipEntry(apksPth, universalAPKEntry, apkPth); err != nil {
+		return "", err
+	}
+
+	return apkPth, nil
+}
+
+// extractZipEntry writes the given entry of the zip archive to the destination path.
+func extractZipEntry(zipPth, entryName, destinationPth string) error {
+	reader, err := zip.OpenReader(zipPth)
+	if err != nil {
+		return fmt.Errorf("failed to open (%s), error: %s", zipPth, err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			logger.Warnf("Failed to close (%s), error: %s", zipPth, err)
+		}
+	}()
+
+	for _, file := range reader.File {
+		if file.Name != entryName {
+			continue
+		}
+
+		source, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open (%s) in (%s), error: %s", entryName, zipPth, err)
+		}
+		defer func() {
+			if err := source.Close(); err != nil {
+				logger.Warnf("Failed to close (%s) in (%s), error: %s", entryName, zipPth, err)
+			}
+		}()
+
+		destination, err := os.Create(destinationPth)
+		if err != nil {
+			return fmt.Errorf("failed to create (%s), error: %s", destinationPth, err)
+		}
+
+		if _, err := io.Copy(destination, source); err != nil {
+			_ = destination.Close()
+			return fmt.Errorf("failed to extract (%s) from (%s), error: %s", entryName, zipPth, err)
+		}
+		return destination.Close()
+	}
+
+	return fmt.Errorf("(%s) not found in (%s)", entryName, zipPth)
+}
@@ -0,0 +1,45 @@
+package project
+
+import "fmt"
+
+// DiagnosticSeverity ...
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticInfo marks an expected missing property, which is defaulted or not needed by the project.
+	DiagnosticInfo DiagnosticSeverity = "info"
+	// DiagnosticWarning marks a problem which may change how the project is built or whether it is built at all.
+	DiagnosticWarning DiagnosticSeverity = "warning"
+)
+
+// Diagnostic is a non-fatal problem found by the project analysis.
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Project  string // Path of the project file, the diagnostic belongs to
+	Field    string // Project property, like ProjectTypeGuids
+	Message  string
+}
+
+// String ...
+func (diagnostic Diagnostic) String() string {
+	return fmt.Sprintf("[%s] %s: %s", diagnostic.Severity, diagnostic.Field, diagnostic.Message)
+}
+
+// HasWarnings reports if any of the project's diagnostics is a warning.
+func (project Model) HasWarnings() bool {
+	for _, diagnostic := range project.Diagnostics {
+		if diagnostic.Severity == DiagnosticWarning {
+			return true
+		}
+	}
+	return false
+}
+
+func (project *Model) diagnose(severity DiagnosticSeverity, pth, field string, err error) {
+	project.Diagnostics = append(project.Diagnostics, Diagnostic{
+		Severity: severity,
+		Project:  pth,
+		Field:    field,
+		Message:  err.Error(),
+	})
+}
@@ -0,0 +1,374 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// ConfigurationPlatformModel ...
+type ConfigurationPlatformModel struct {
+	Configuration string
+	Platform      string
+	OutputDir     string
+
+	MtouchArchs []string
+	BuildIpa    bool
+
+	SignAndroid          bool
+	AndroidABIs          []string
+	AndroidPackagePerABI bool
+}
+
+// Model ...
+type Model struct {
+	Pth  string
+	Name string // Set by solution analyze or set its path's filename without extension
+
+	// Solution Configuration|Platform - Project Configuration|Platform map
+	// !!! only set by solution analyze
+	ConfigMap map[string]string
+
+	ID            string
+	SDK           constants.SDK
+	Kind          constants.ProjectKind
+	TestFramework constants.TestFramework
+	OutputType    string
+	AssemblyName  string
+
+	ReferredProjectIDs  []string
+	EmbeddedProjectPths []string // App extension and watch app projects built into this app
+
+	SDKStyle          bool
+	TargetFrameworks  []string
+	PackageReferences []string
+
+	ManifestPth        string
+	AndroidApplication bool
+
+	Diagnostics []Diagnostic // Problems found by the analysis, explaining the project's missing or unknown properties
+
+	Configs map[string]ConfigurationPlatformModel // Project Configuration|Platform - ConfigurationPlatformModel map
+}
+
+// New ...
+func New(pth string) (Model, error) {
+	return analyzeProject(pth)
+}
+
+// MappedConfig returns the project Configuration|Platform mapped to the given solution Configuration|Platform.
+func (project Model) MappedConfig(solutionConfig string) (string, bool) {
+	return utility.LookupConfig(project.ConfigMap, solutionConfig)
+}
+
+// Config returns the project configuration of the given project Configuration|Platform.
+func (project Model) Config(config string) (ConfigurationPlatformModel, bool) {
+	if configModel, ok := project.Configs[config]; ok {
+		return configModel, true
+	}
+
+	for key, configModel := range project.Configs {
+		if utility.ConfigsEqual(key, config) {
+			return configModel, true
+		}
+	}
+
+	return ConfigurationPlatformModel{}, false
+}
+
+func analyzeTargetDefinition(projectModel Model, pth string) (Model, error) {
+	projectDir := filepath.Dir(pth)
+	var err error
+
+	parsedProject, err := ParseProject(pth)
+	if err != nil {
+		return Model{}, err
+	}
+
+	for _, importedProject := range GetImportedProjects(parsedProject) {
+		if !strings.Contains(importedProject, "$(MSBuild") {
+			targetDefinitionPth := filepath.Join(projectDir, importedProject)
+
+			if exist, err := pathutil.IsPathExists(targetDefinitionPth); err != nil {
+				return Model{}, err
+			} else if exist {
+				importingModel := projectModel
+				importingModel.Diagnostics = nil
+
+				projectFromTargetDefinition, err := analyzeTargetDefinition(importingModel, targetDefinitionPth)
+				if err != nil {
+					return Model{}, err
+				}
+
+				// Set properties became from solution analyze
+				projectFromTargetDefinition.Name = projectModel.Name
+				projectFromTargetDefinition.Pth = projectModel.Pth
+				projectFromTargetDefinition.ConfigMap = projectModel.ConfigMap
+				// ---
+
+				// the imported definition's diagnostics name their own file, keep them after the importing project's ones
+				projectFromTargetDefinition.Diagnostics = append(projectModel.Diagnostics, projectFromTargetDefinition.Diagnostics...)
+
+				projectModel = projectFromTargetDefinition
+			}
+		}
+	}
+
+	sdkStyle := parsedProject.Sdk != ""
+
+	projectModel.ID, err = GetProjectGUID(parsedProject)
+	if err != nil {
+		projectModel.diagnose(DiagnosticInfo, pth, "ProjectGuid", err)
+	}
+
+	projectModel.OutputType, err = GetOutputType(parsedProject)
+	if err != nil {
+		// SDK-style libraries usually rely on the default output type
+		severity := DiagnosticWarning
+		if sdkStyle {
+			severity = DiagnosticInfo
+		}
+		projectModel.diagnose(severity, pth, "OutputType", fmt.Errorf("%s, the project builds a library", err))
+	}
+
+	projectModel.AssemblyName, err = GetAssemblyName(parsedProject)
+	if err != nil {
+		projectModel.diagnose(DiagnosticInfo, pth, "AssemblyName", err)
+	}
+
+	projectModel.TestFramework, err = GetTestFramework(parsedProject)
+	if err != nil {
+		projectModel.diagnose(DiagnosticInfo, pth, "TestFramework", err)
+	}
+
+	projectType, err := GetResolvedProjectType(parsedProject)
+	projectModel.SDK = projectType.SDK
+	projectModel.Kind = projectType.Kind
+	if err != nil {
+		// SDK-style projects do not list project type GUIDs
+		severity := DiagnosticWarning
+		if sdkStyle || projectModel.OutputType != "exe" {
+			severity = DiagnosticInfo
+		}
+		projectModel.diagnose(severity, pth, "ProjectTypeGuids", err)
+	} else if projectModel.SDK == constants.SDKUnknown {
+		guids, _ := GetProjectTypeGUIDs(parsedProject)
+		projectModel.diagnose(DiagnosticWarning, pth, "ProjectTypeGuids", fmt.Errorf("project type GUIDs (%s) do not match any supported platform", guids))
+	}
+
+	if sdkStyle {
+		if frameworks, err := GetTargetFrameworks(parsedProject); err == nil {
+			projectModel.TargetFrameworks = frameworks
+		}
+
+		// SDK-style projects do not list project type GUIDs, their platform is part of the target framework
+		if projectModel.SDK == constants.SDKUnknown {
+			projectModel.SDK, err = GetSDKFromTargetFrameworks(parsedProject)
+			if err != nil {
+				severity := DiagnosticInfo
+				if projectModel.OutputType == "exe" {
+					severity = DiagnosticWarning
+				}
+				projectModel.diagnose(severity, pth, "TargetFrameworks", err)
+			}
+		}
+	}
+
+	if projectModel.SDK == constants.SDKAndroid {
+		projectModel.ManifestPth, err = GetResolvedAndroidManifestPath(parsedProject, projectDir)
+		if err != nil {
+			projectModel.diagnose(DiagnosticInfo, pth, "AndroidManifest", err)
+		}
+
+		projectModel.AndroidApplication, err = GetIsAndroidApplication(parsedProject)
+		if err != nil {
+			// SDK-style Android applications are executables
+			if sdkStyle {
+				projectModel.AndroidApplication = projectModel.OutputType == "exe"
+				projectModel.diagnose(DiagnosticInfo, pth, "AndroidApplication", err)
+			} else {
+				projectModel.diagnose(DiagnosticWarning, pth, "AndroidApplication", fmt.Errorf("%s, the project is not an Android application", err))
+			}
+		}
+	}
+
+	// the tvOS app extensions share the tvOS project type GUID, like the SDK-style extensions they set IsAppExtension
+	if isAppExtension, err := GetIsAppExtension(parsedProject); err == nil && isAppExtension {
+		projectModel.Kind = constants.ProjectKindAppExtension
+	}
+	if sdkStyle {
+		if isBindingProject, err := GetIsBindingProject(parsedProject); err == nil && isBindingProject {
+			projectModel.Kind = constants.ProjectKindBinding
+		}
+	}
+
+	projectModel.ReferredProjectIDs = GetReferencedProjectIds(parsedProject)
+	projectModel.EmbeddedProjectPths = GetEmbeddedProjectPaths(parsedProject, projectDir)
+
+	if sdkStyle {
+		projectModel.SDKStyle = true
+	}
+	projectModel.PackageReferences = append(projectModel.PackageReferences, GetPackageReferences(parsedProject)...)
+
+	configPlatforms, err := GetPropertyGroupsConfiguration(parsedProject, projectDir, projectModel.SDK)
+	if err != nil {
+		projectModel.diagnose(DiagnosticWarning, pth, "PropertyGroup", err)
+	}
+
+	for _, configPlatform := range configPlatforms {
+		projectModel.Configs[utility.ToConfig(configPlatform.Configuration, configPlatform.Platform)] = configPlatform
+	}
+
+	return projectModel, nil
+}
+
+func analyzeProject(pth string) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to expand path (%s), error: %s", pth, err)
+	}
+
+	fileName := filepath.Base(absPth)
+	ext := filepath.Ext(absPth)
+	fileName = strings.TrimSuffix(fileName, ext)
+
+	project := Model{
+		Pth:           absPth,
+		Name:          fileName,
+		ConfigMap:     map[string]string{},
+		Configs:       map[string]ConfigurationPlatformModel{},
+		SDK:           constants.SDKUnknown,
+		Kind:          constants.ProjectKindUnknown,
+		TestFramework: constants.TestFrameworkUnknown,
+	}
+
+	project, err = analyzeTargetDefinition(project, absPth)
+	if err != nil {
+		return Model{}, err
+	}
+
+	if project.SDKStyle {
+		addSDKStyleDefaults(&project, filepath.Dir(absPth), fileName)
+	}
+
+	if project.Kind == constants.ProjectKindUnknown {
+		project.Kind = resolveProjectKind(project)
+	}
+
+	return project, nil
+}
+
+// AddSDKStyleDefaultConfigs adds the implicit Debug|AnyCPU and Release|AnyCPU configurations of the SDK-style project,
+// which are not defined in the project file. It is meant for building a project without a solution,
+// the solution configs otherwise map to the project's defined configurations.
+// The property groups conditioned on the configuration only apply to its AnyCPU configuration,
+// merged over the defaults and under the property group of the configuration's AnyCPU platform, if any.
+func (project *Model) AddSDKStyleDefaultConfigs() {
+	if !project.SDKStyle {
+		return
+	}
+
+	configurations := []string{"Debug", "Release"}
+	configurationOnlyConfigs := map[string]ConfigurationPlatformModel{}
+	for _, configModel := range project.Configs {
+		if configModel.Configuration == "" || configModel.Platform != "" {
+			continue
+		}
+
+		key := strings.ToLower(configModel.Configuration)
+		if key != "debug" && key != "release" {
+			configurations = append(configurations, configModel.Configuration)
+		}
+		configurationOnlyConfigs[key] = configModel
+	}
+
+	for _, configuration := range configurations {
+		configModel := ConfigurationPlatformModel{
+			Configuration: configuration,
+			Platform:      "AnyCPU",
+			OutputDir:     filepath.Join(filepath.Dir(project.Pth), "bin", configuration),
+		}
+
+		if configurationOnly, ok := configurationOnlyConfigs[strings.ToLower(configuration)]; ok {
+			configModel = mergeConfig(configModel, configurationOnly)
+		}
+
+		config := utility.ToConfig(configuration, "AnyCPU")
+		for key, existing := range project.Configs {
+			if utility.ConfigsEqual(key, config) {
+				config = key
+				configModel = mergeConfig(configModel, existing)
+				configModel.Platform = existing.Platform
+				break
+			}
+		}
+
+		project.Configs[config] = configModel
+	}
+}
+
+// mergeConfig returns the base configuration with the properties set by the override.
+func mergeConfig(base, override ConfigurationPlatformModel) ConfigurationPlatformModel {
+	if override.OutputDir != "" {
+		base.OutputDir = override.OutputDir
+	}
+	if len(override.MtouchArchs) > 0 {
+		base.MtouchArchs = override.MtouchArchs
+	}
+	if len(override.AndroidABIs) > 0 {
+		base.AndroidABIs = override.AndroidABIs
+	}
+	base.BuildIpa = base.BuildIpa || override.BuildIpa
+	base.SignAndroid = base.SignAndroid || override.SignAndroid
+	base.AndroidPackagePerABI = base.AndroidPackagePerABI || override.AndroidPackagePerABI
+	return base
+}
+
+// resolveProjectKind tells apart the apps and the libraries of the project types shared by both.
+func resolveProjectKind(project Model) constants.ProjectKind {
+	switch project.SDK {
+	case constants.SDKUnknown:
+		return constants.ProjectKindUnknown
+	case constants.SDKAndroid:
+		if project.AndroidApplication {
+			return constants.ProjectKindApp
+		}
+	default:
+		if project.OutputType == "exe" {
+			return constants.ProjectKindApp
+		}
+	}
+	return constants.ProjectKindLibrary
+}
+
+// addSDKStyleDefaults sets the implicit properties of the SDK-style projects:
+// the assembly is named after the project file and the outputs are generated into bin/<Configuration>,
+// unless a property group of the configuration sets the OutputPath.
+func addSDKStyleDefaults(project *Model, projectDir, projectFileName string) {
+	if project.AssemblyName == "" {
+		project.AssemblyName = projectFileName
+	}
+
+	// a property group conditioned on the configuration only sets the OutputPath of each platform
+	configurationOutputDirs := map[string]string{}
+	for _, configModel := range project.Configs {
+		if configModel.Platform == "" && configModel.OutputDir != "" {
+			configurationOutputDirs[strings.ToLower(configModel.Configuration)] = configModel.OutputDir
+		}
+	}
+
+	for config, configModel := range project.Configs {
+		if configModel.OutputDir == "" && configModel.Configuration != "" {
+			outputDir, ok := configurationOutputDirs[strings.ToLower(configModel.Configuration)]
+			if !ok {
+				outputDir = filepath.Join(projectDir, "bin", configModel.Configuration)
+			}
+			configModel.OutputDir = outputDir
+			project.Configs[config] = configModel
+		}
+	}
+}
@@ -8,15 +8,16 @@ import (
 	"strings"
 
 	"github.com/bitrise-io/go-utils/fileutil"
-	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-xamarin/constants"
-	"github.com/bitrise-io/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
 )
 
 // Project is the struct for the csproj file.
 type Project struct {
 	XMLName        xml.Name        `xml:"Project"`
 	Text           string          `xml:",chardata"`
+	Sdk            string          `xml:"Sdk,attr"`
 	DefaultTargets string          `xml:"DefaultTargets,attr"`
 	ToolsVersion   string          `xml:"ToolsVersion,attr"`
 	Xmlns          string          `xml:"xmlns,attr"`
@@ -46,33 +47,38 @@ type PropertyGroup struct {
 		Text      string `xml:",chardata"`
 		Condition string `xml:"Condition,attr"`
 	} `xml:"Platform"`
-	ProjectGUID               []string `xml:"ProjectGuid"`
-	ProjectTypeGuids          []string `xml:"ProjectTypeGuids"`
-	OutputType                []string `xml:"OutputType"`
-	RootNamespace             []string `xml:"RootNamespace"`
-	AssemblyName              []string `xml:"AssemblyName"`
-	TargetFrameworkVersion    []string `xml:"TargetFrameworkVersion"`
-	AndroidApplication        []string `xml:"AndroidApplication"`
-	AndroidManifest           []string `xml:"AndroidManifest"`
-	AndroidResgenFile         []string `xml:"AndroidResgenFile"`
-	AndroidResgenClass        []string `xml:"AndroidResgenClass"`
-	MonoAndroidResourcePrefix []string `xml:"MonoAndroidResourcePrefix"`
-	MonoAndroidAssetsPrefix   []string `xml:"MonoAndroidAssetsPrefix"`
-	DebugSymbols              []string `xml:"DebugSymbols"`
-	DebugType                 []string `xml:"DebugType"`
-	Optimize                  []string `xml:"Optimize"`
-	OutputPath                []string `xml:"OutputPath"`
-	DefineConstants           []string `xml:"DefineConstants"`
-	ErrorReport               []string `xml:"ErrorReport"`
-	WarningLevel              []string `xml:"WarningLevel"`
-	AndroidLinkMode           []string `xml:"AndroidLinkMode"`
-	AndroidManagedSymbols     []string `xml:"AndroidManagedSymbols"`
-	AndroidUseSharedRuntime   []string `xml:"AndroidUseSharedRuntime"`
-	MandroidI18n              []string `xml:"MandroidI18n"`
-	MtouchArch                []string `xml:"MtouchArch"`
-	AndroidSupportedAbis      []string `xml:"AndroidSupportedAbis"`
-	BuildIpa                  []string `xml:"BuildIpa"`
-	AndroidKeyStore           []string `xml:"AndroidKeyStore"`
+	ProjectGUID                []string `xml:"ProjectGuid"`
+	ProjectTypeGuids           []string `xml:"ProjectTypeGuids"`
+	OutputType                 []string `xml:"OutputType"`
+	RootNamespace              []string `xml:"RootNamespace"`
+	AssemblyName               []string `xml:"AssemblyName"`
+	TargetFrameworkVersion     []string `xml:"TargetFrameworkVersion"`
+	TargetFramework            []string `xml:"TargetFramework"`
+	TargetFrameworks           []string `xml:"TargetFrameworks"`
+	AndroidApplication         []string `xml:"AndroidApplication"`
+	AndroidManifest            []string `xml:"AndroidManifest"`
+	AndroidResgenFile          []string `xml:"AndroidResgenFile"`
+	AndroidResgenClass         []string `xml:"AndroidResgenClass"`
+	MonoAndroidResourcePrefix  []string `xml:"MonoAndroidResourcePrefix"`
+	MonoAndroidAssetsPrefix    []string `xml:"MonoAndroidAssetsPrefix"`
+	DebugSymbols               []string `xml:"DebugSymbols"`
+	DebugType                  []string `xml:"DebugType"`
+	Optimize                   []string `xml:"Optimize"`
+	OutputPath                 []string `xml:"OutputPath"`
+	DefineConstants            []string `xml:"DefineConstants"`
+	ErrorReport                []string `xml:"ErrorReport"`
+	WarningLevel               []string `xml:"WarningLevel"`
+	AndroidLinkMode            []string `xml:"AndroidLinkMode"`
+	AndroidManagedSymbols      []string `xml:"AndroidManagedSymbols"`
+	AndroidUseSharedRuntime    []string `xml:"AndroidUseSharedRuntime"`
+	MandroidI18n               []string `xml:"MandroidI18n"`
+	MtouchArch                 []string `xml:"MtouchArch"`
+	AndroidSupportedAbis       []string `xml:"AndroidSupportedAbis"`
+	AndroidCreatePackagePerAbi []string `xml:"AndroidCreatePackagePerAbi"`
+	BuildIpa                   []string `xml:"BuildIpa"`
+	AndroidKeyStore            []string `xml:"AndroidKeyStore"`
+	IsAppExtension             []string `xml:"IsAppExtension"`
+	IsBindingProject           []string `xml:"IsBindingProject"`
 }
 
 // ItemGroup the item group from the csproj file.
@@ -96,6 +102,11 @@ type ItemGroup struct {
 		Text    string `xml:",chardata"`
 		Include string `xml:"Include,attr"`
 	} `xml:"AndroidResource"`
+	PackageReferences []struct {
+		Text    string `xml:",chardata"`
+		Include string `xml:"Include,attr"`
+		Version string `xml:"Version,attr"`
+	} `xml:"PackageReference"`
 }
 
 // ProjReference the project reference from the csproj file.
@@ -107,6 +118,8 @@ type ProjReference struct {
 	Name                    string   `xml:"Name"`
 	ReferenceOutputAssembly string   `xml:"ReferenceOutputAssembly"`
 	Private                 string   `xml:"Private"`
+	IsAppExtension          string   `xml:"IsAppExtension"`
+	IsWatchApp              string   `xml:"IsWatchApp"`
 }
 
 const getterErrorMsg = "could not find %s"
@@ -149,7 +162,7 @@ func GetOutputType(project Project) (string, error) {
 			return strings.ToLower(propertyGroup.OutputType[length-1]), nil
 		}
 	}
-	return "", fmt.Errorf(getterErrorMsg, "project guid")
+	return "", fmt.Errorf(getterErrorMsg, "output type")
 }
 
 // GetAssemblyName gets the assembly name from the given project.
@@ -205,6 +218,13 @@ func GetConfiguration(propertyGroup PropertyGroup) (string, error) {
 	return "", fmt.Errorf(getterErrorMsg, "configuration")
 }
 
+// The patterns of the property group conditions, compiled once as every property group is matched against them.
+var (
+	configurationPlatformConditionRegexp = regexp.MustCompile(`'\$\(Configuration\)\|\$\(Platform\)'\s*==\s*'(?P<config>.*)\|(?P<platform>.*)'`)
+	configurationConditionRegexp         = regexp.MustCompile(`'\$\(Configuration\)'\s*==\s*'(?P<config>.*)'`)
+	platformConditionRegexp              = regexp.MustCompile(`'\$\(Platform\)'\s*==\s*'(?P<platform>.*)'`)
+)
+
 // GetPropertyGroupCondition gets the condition of the given property group.
 func GetPropertyGroupCondition(propertyGroup PropertyGroup) (string, error) {
 	if propertyGroup.Condition == "" {
@@ -219,11 +239,11 @@ func GetResolvedConfiguration(propertyGroup PropertyGroup) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if matches := regexp.MustCompile(`'\$\(Configuration\)\|\$\(Platform\)'\s*==\s*'(?P<config>.*)\|(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 3 {
+	if matches := configurationPlatformConditionRegexp.FindStringSubmatch(conditionText); len(matches) == 3 {
 		return matches[1], nil
 	}
 
-	if matches := regexp.MustCompile(`'\$\(Configuration\)'\s*==\s*'(?P<config>.*)'`).FindStringSubmatch(conditionText); len(matches) == 2 {
+	if matches := configurationConditionRegexp.FindStringSubmatch(conditionText); len(matches) == 2 {
 		return matches[1], nil
 	}
 
@@ -231,19 +251,24 @@ func GetResolvedConfiguration(propertyGroup PropertyGroup) (string, error) {
 }
 
 // GetResolvedPlatform gets the resolved platform from the given property group.
+// A condition on the configuration only applies to every platform, its platform is empty.
 func GetResolvedPlatform(propertyGroup PropertyGroup) (string, error) {
 	conditionText, err := GetPropertyGroupCondition(propertyGroup)
 	if err != nil {
 		return "", err
 	}
-	if matches := regexp.MustCompile(`'\$\(Configuration\)\|\$\(Platform\)'\s*==\s*'(?P<config>.*)\|(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 3 {
+	if matches := configurationPlatformConditionRegexp.FindStringSubmatch(conditionText); len(matches) == 3 {
 		return matches[2], nil
 	}
 
-	if matches := regexp.MustCompile(`'\$\(Platform\)'\s*==\s*'(?P<platform>.*)'`).FindStringSubmatch(conditionText); len(matches) == 2 {
+	if matches := platformConditionRegexp.FindStringSubmatch(conditionText); len(matches) == 2 {
 		return matches[1], nil
 	}
 
+	if configurationConditionRegexp.MatchString(conditionText) {
+		return "", nil
+	}
+
 	return conditionText, nil
 }
 
@@ -314,6 +339,30 @@ func GetAndroidKeyStore(propertyGroup PropertyGroup) (bool, error) {
 	return false, fmt.Errorf(getterErrorMsg, "Android keystore")
 }
 
+// GetAndroidSupportedAbis gets the Android ABIs from the given property group.
+func GetAndroidSupportedAbis(propertyGroup PropertyGroup) ([]string, error) {
+	length := len(propertyGroup.AndroidSupportedAbis)
+	if length > 0 {
+		abis := []string{}
+		for _, abi := range utility.SplitAndStripList(strings.Replace(propertyGroup.AndroidSupportedAbis[length-1], ",", ";", -1), ";") {
+			if abi != "" {
+				abis = append(abis, abi)
+			}
+		}
+		return abis, nil
+	}
+	return []string{}, fmt.Errorf(getterErrorMsg, "Android supported ABIs")
+}
+
+// GetAndroidCreatePackagePerAbi gets the per-ABI package boolean from the given property group.
+func GetAndroidCreatePackagePerAbi(propertyGroup PropertyGroup) (bool, error) {
+	length := len(propertyGroup.AndroidCreatePackagePerAbi)
+	if length > 0 {
+		return boolParse(propertyGroup.AndroidCreatePackagePerAbi[length-1]), nil
+	}
+	return false, fmt.Errorf(getterErrorMsg, "Android create package per ABI")
+}
+
 // GetProjectTypeGUIDs gets the project type GUIDs from the given project.
 func GetProjectTypeGUIDs(project Project) (string, error) {
 	for _, propertyGroup := range project.PropertyGroups {
@@ -325,23 +374,92 @@ func GetProjectTypeGUIDs(project Project) (string, error) {
 	return "", fmt.Errorf(getterErrorMsg, "project GUIDs")
 }
 
-// GetResolvedProjectTypeGUIDs gets the project type GUIDs from the given project.
-func GetResolvedProjectTypeGUIDs(project Project) (constants.SDK, error) {
-	sdk := constants.SDKUnknown
+// GetTargetFrameworks gets the target framework monikers of the given SDK-style project.
+func GetTargetFrameworks(project Project) ([]string, error) {
+	for _, propertyGroup := range project.PropertyGroups {
+		if length := len(propertyGroup.TargetFrameworks); length > 0 {
+			frameworks := []string{}
+			for _, framework := range utility.SplitAndStripList(propertyGroup.TargetFrameworks[length-1], ";") {
+				if framework != "" {
+					frameworks = append(frameworks, framework)
+				}
+			}
+			return frameworks, nil
+		}
+		if length := len(propertyGroup.TargetFramework); length > 0 {
+			return []string{strings.TrimSpace(propertyGroup.TargetFramework[length-1])}, nil
+		}
+	}
+	return nil, fmt.Errorf(getterErrorMsg, "target frameworks")
+}
+
+// GetSDKFromTargetFrameworks gets the SDK of the first mobile target framework of the given SDK-style project.
+func GetSDKFromTargetFrameworks(project Project) (constants.SDK, error) {
+	frameworks, err := GetTargetFrameworks(project)
+	if err != nil {
+		return constants.SDKUnknown, err
+	}
+
+	for _, framework := range frameworks {
+		if sdk, err := constants.ParseTargetFramework(framework); err == nil {
+			return sdk, nil
+		}
+	}
+	return constants.SDKUnknown, fmt.Errorf(getterErrorMsg, "mobile target framework")
+}
+
+// GetResolvedProjectType gets the first registered project type of the given project's project type GUIDs.
+func GetResolvedProjectType(project Project) (constants.ProjectType, error) {
 	guidsLine, err := GetProjectTypeGUIDs(project)
 	if err != nil {
-		return sdk, err
+		return constants.ProjectType{SDK: constants.SDKUnknown, Kind: constants.ProjectKindUnknown}, err
 	}
-	projectTypeList := strings.Split(guidsLine, ";")
-	for _, guid := range projectTypeList {
-		guid = trimIDFixes(guid)
 
-		sdk, err = constants.ParseProjectTypeGUID(guid)
-		if err == nil {
-			break
+	for _, guid := range strings.Split(guidsLine, ";") {
+		if projectType, ok := constants.LookupProjectType(guid); ok {
+			return projectType, nil
 		}
 	}
-	return sdk, nil
+	return constants.ProjectType{SDK: constants.SDKUnknown, Kind: constants.ProjectKindUnknown}, nil
+}
+
+// GetResolvedProjectTypeGUIDs gets the project type GUIDs from the given project.
+func GetResolvedProjectTypeGUIDs(project Project) (constants.SDK, error) {
+	projectType, err := GetResolvedProjectType(project)
+	return projectType.SDK, err
+}
+
+// GetIsAppExtension gets the bool value if the project is an app extension.
+func GetIsAppExtension(project Project) (bool, error) {
+	for _, propertyGroup := range project.PropertyGroups {
+		length := len(propertyGroup.IsAppExtension)
+		if length > 0 {
+			return boolParse(propertyGroup.IsAppExtension[length-1]), nil
+		}
+	}
+	return false, fmt.Errorf(getterErrorMsg, "app extension")
+}
+
+// GetIsBindingProject gets the bool value if the SDK-style project is a binding library.
+func GetIsBindingProject(project Project) (bool, error) {
+	for _, propertyGroup := range project.PropertyGroups {
+		length := len(propertyGroup.IsBindingProject)
+		if length > 0 {
+			return boolParse(propertyGroup.IsBindingProject[length-1]), nil
+		}
+	}
+	return false, fmt.Errorf(getterErrorMsg, "binding project")
+}
+
+// GetEmbeddedProjectPaths gets the paths of the app extension and watch app projects embedded into the given project.
+func GetEmbeddedProjectPaths(project Project, projectDir string) []string {
+	var pths []string
+	for _, projectReference := range GetProjectReferences(project) {
+		if boolParse(projectReference.IsAppExtension) || boolParse(projectReference.IsWatchApp) {
+			pths = append(pths, filepath.Join(projectDir, utility.FixWindowsPath(projectReference.Include)))
+		}
+	}
+	return pths
 }
 
 // GetItemGroupIncludes gets the includes from the given item group.
@@ -399,6 +517,19 @@ func GetReferencedProjectIds(project Project) []string {
 	return projectIds
 }
 
+// GetPackageReferences gets the included package names of the PackageReference items.
+func GetPackageReferences(project Project) []string {
+	var packageReferences []string
+	for _, itemGroup := range project.ItemGroups {
+		for _, packageReference := range itemGroup.PackageReferences {
+			if packageReference.Include != "" {
+				packageReferences = append(packageReferences, packageReference.Include)
+			}
+		}
+	}
+	return packageReferences
+}
+
 // GetImportedProjects gets the imported projects from a given project.
 func GetImportedProjects(project Project) []string {
 	var importedProjects []string
@@ -446,6 +577,16 @@ func GetPropertyGroupsConfiguration(project Project, projectDir string, sdk cons
 			if err != nil {
 				debugParseLog(err)
 			}
+
+			configModel.AndroidABIs, err = GetAndroidSupportedAbis(propertyGroup)
+			if err != nil {
+				debugParseLog(err)
+			}
+
+			configModel.AndroidPackagePerABI, err = GetAndroidCreatePackagePerAbi(propertyGroup)
+			if err != nil {
+				debugParseLog(err)
+			}
 		}
 
 		configModels = append(configModels, configModel)
@@ -464,5 +605,5 @@ func trimIDFixes(id string) string {
 }
 
 func debugParseLog(err error) {
-	log.Debugf("%v", err)
+	logging.Debugf("%v", err)
 }
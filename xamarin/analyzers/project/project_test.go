@@ -0,0 +1,95 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+)
+
+func TestNewImportedDiagnostics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	files := map[string]string{
+		"App.csproj": `<?xml version="1.0" encoding="utf-8"?>
+<Project DefaultTargets="Build" ToolsVersion="4.0" xmlns="http://schemas.microsoft.com/developer/msbuild/2003">
+  <Import Project="Shared.targets" />
+  <PropertyGroup>
+    <ProjectTypeGuids>{FEACFBD2-3405-455C-9665-78FE426C6842};{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}</ProjectTypeGuids>
+    <OutputType>Exe</OutputType>
+  </PropertyGroup>
+</Project>
+`,
+		"Shared.targets": `<?xml version="1.0" encoding="utf-8"?>
+<Project ToolsVersion="4.0" xmlns="http://schemas.microsoft.com/developer/msbuild/2003">
+  <PropertyGroup>
+    <AssemblyName>Shared</AssemblyName>
+  </PropertyGroup>
+</Project>
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proj, err := New(filepath.Join(dir, "App.csproj"))
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+
+	diagnosedFiles := map[string]bool{}
+	for _, diagnostic := range proj.Diagnostics {
+		diagnosedFiles[filepath.Base(diagnostic.Project)] = true
+	}
+	for name := range files {
+		if !diagnosedFiles[name] {
+			t.Errorf("Diagnostics = %v, want diagnostics of %s", proj.Diagnostics, name)
+		}
+	}
+}
+
+func TestNewTvOSAppExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	pth := filepath.Join(dir, "Extension.csproj")
+	content := `<?xml version="1.0" encoding="utf-8"?>
+<Project DefaultTargets="Build" ToolsVersion="4.0" xmlns="http://schemas.microsoft.com/developer/msbuild/2003">
+  <PropertyGroup>
+    <ProjectTypeGuids>{06FA79CB-D6CD-4721-BB4B-1BD202089C55};{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}</ProjectTypeGuids>
+    <OutputType>Library</OutputType>
+    <IsAppExtension>True</IsAppExtension>
+  </PropertyGroup>
+</Project>
+`
+	if err := ioutil.WriteFile(pth, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	proj, err := New(pth)
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+	if proj.SDK != constants.SDKTvOS || proj.Kind != constants.ProjectKindAppExtension {
+		t.Errorf("SDK, Kind = %s, %s, want %s, %s", proj.SDK, proj.Kind, constants.SDKTvOS, constants.ProjectKindAppExtension)
+	}
+}
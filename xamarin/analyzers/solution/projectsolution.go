@@ -0,0 +1,53 @@
+package solution
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// analyzeProjectAsSolution synthesizes a solution of a single project:
+// the solution configs are the project's own configurations, mapped to themselves.
+func analyzeProjectAsSolution(pth string) (Model, error) {
+	proj, err := project.New(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to analyze project (%s), error: %s", pth, err)
+	}
+
+	solution := newModel(proj.Pth)
+
+	proj.AddSDKStyleDefaultConfigs()
+
+	for _, configModel := range proj.Configs {
+		if !isResolvedConfig(configModel) {
+			continue
+		}
+
+		config := utility.ToConfig(configModel.Configuration, configModel.Platform)
+		solution.ConfigMap[config] = config
+		proj.ConfigMap[config] = config
+	}
+
+	if len(solution.ConfigMap) == 0 {
+		return Model{}, fmt.Errorf("project (%s) does not define any Configuration|Platform", proj.Pth)
+	}
+
+	projectID := proj.ID
+	if projectID == "" {
+		projectID = pathProjectID(proj.Pth)
+	}
+	solution.ProjectMap[projectID] = proj
+
+	return solution, nil
+}
+
+// isResolvedConfig reports whether the configuration and platform of a property group could be read from its condition,
+// unresolved conditions are kept as is by the project parser.
+func isResolvedConfig(configModel project.ConfigurationPlatformModel) bool {
+	if configModel.Configuration == "" || configModel.Platform == "" {
+		return false
+	}
+	return !strings.ContainsAny(configModel.Configuration+configModel.Platform, "$'=")
+}
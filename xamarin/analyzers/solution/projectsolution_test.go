@@ -0,0 +1,127 @@
+package solution
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+)
+
+const sdkStyleProjectStart = `<Project Sdk="Microsoft.NET.Sdk">
+  <PropertyGroup>
+    <TargetFramework>net6.0-ios</TargetFramework>
+    <OutputType>Exe</OutputType>
+  </PropertyGroup>
+`
+
+func TestAnalyzeProjectAsSolution(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantConfigs map[string]project.ConfigurationPlatformModel // relative OutputDir
+	}{
+		{
+			name:    "implicit configs",
+			content: sdkStyleProjectStart + `</Project>`,
+			wantConfigs: map[string]project.ConfigurationPlatformModel{
+				"Debug|AnyCPU":   {Configuration: "Debug", Platform: "AnyCPU", OutputDir: "bin/Debug"},
+				"Release|AnyCPU": {Configuration: "Release", Platform: "AnyCPU", OutputDir: "bin/Release"},
+			},
+		},
+		{
+			name: "Release only property group",
+			content: sdkStyleProjectStart + `  <PropertyGroup Condition="'$(Configuration)'=='Release'">
+    <OutputPath>out\Release</OutputPath>
+    <MtouchArch>ARM64</MtouchArch>
+    <BuildIpa>true</BuildIpa>
+  </PropertyGroup>
+</Project>`,
+			wantConfigs: map[string]project.ConfigurationPlatformModel{
+				"Debug|AnyCPU":   {Configuration: "Debug", Platform: "AnyCPU", OutputDir: "bin/Debug"},
+				"Release|AnyCPU": {Configuration: "Release", Platform: "AnyCPU", OutputDir: "out/Release", MtouchArchs: []string{"ARM64"}, BuildIpa: true},
+			},
+		},
+		{
+			name: "Release only and Release|AnyCPU property groups",
+			content: sdkStyleProjectStart + `  <PropertyGroup Condition=" '$(Configuration)' == 'Release' ">
+    <OutputPath>out\Release</OutputPath>
+    <MtouchArch>ARMv7</MtouchArch>
+  </PropertyGroup>
+  <PropertyGroup Condition=" '$(Configuration)|$(Platform)' == 'Release|AnyCPU' ">
+    <MtouchArch>ARM64</MtouchArch>
+  </PropertyGroup>
+</Project>`,
+			wantConfigs: map[string]project.ConfigurationPlatformModel{
+				"Debug|AnyCPU":   {Configuration: "Debug", Platform: "AnyCPU", OutputDir: "bin/Debug"},
+				"Release|AnyCPU": {Configuration: "Release", Platform: "AnyCPU", OutputDir: "out/Release", MtouchArchs: []string{"ARM64"}},
+			},
+		},
+		{
+			name: "custom configuration only property group",
+			content: sdkStyleProjectStart + `  <PropertyGroup Condition="'$(Configuration)'=='AppStore'">
+    <MtouchArch>ARM64</MtouchArch>
+  </PropertyGroup>
+</Project>`,
+			wantConfigs: map[string]project.ConfigurationPlatformModel{
+				"Debug|AnyCPU":    {Configuration: "Debug", Platform: "AnyCPU", OutputDir: "bin/Debug"},
+				"Release|AnyCPU":  {Configuration: "Release", Platform: "AnyCPU", OutputDir: "bin/Release"},
+				"AppStore|AnyCPU": {Configuration: "AppStore", Platform: "AnyCPU", OutputDir: "bin/AppStore", MtouchArchs: []string{"ARM64"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "project")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Log(err)
+				}
+			}()
+
+			pth := filepath.Join(dir, "App.csproj")
+			if err := ioutil.WriteFile(pth, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			solution, err := New(pth, true)
+			if err != nil {
+				t.Fatalf("New() error = %s", err)
+			}
+
+			wantConfigList := []string{}
+			for config := range tt.wantConfigs {
+				wantConfigList = append(wantConfigList, config)
+			}
+			sort.Strings(wantConfigList)
+			if configList := solution.ConfigList(); !reflect.DeepEqual(configList, wantConfigList) {
+				t.Errorf("ConfigList() = %v, want %v", configList, wantConfigList)
+			}
+
+			if len(solution.ProjectMap) != 1 {
+				t.Fatalf("ProjectMap = %v, want 1 project", solution.ProjectMap)
+			}
+			for _, proj := range solution.ProjectMap {
+				for config, want := range tt.wantConfigs {
+					want.OutputDir = filepath.Join(dir, want.OutputDir)
+
+					got, ok := proj.Config(config)
+					if !ok {
+						t.Errorf("Config(%s) not found", config)
+						continue
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("Config(%s) = %+v, want %+v", config, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package solution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// solutionFilter is the content of a .slnf solution filter file,
+// the base solution path is relative to the filter, the project paths are relative to the base solution.
+type solutionFilter struct {
+	Solution struct {
+		Path     string   `json:"path"`
+		Projects []string `json:"projects"`
+	} `json:"solution"`
+}
+
+func analyzeSolutionFilter(pth string, analyzeProjects bool) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("Failed to expand path (%s), error: %s", pth, err)
+	}
+
+	content, err := os.ReadFile(absPth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to read solution filter (%s), error: %s", absPth, err)
+	}
+
+	var filter solutionFilter
+	if err := json.Unmarshal(content, &filter); err != nil {
+		return Model{}, fmt.Errorf("failed to parse solution filter (%s), error: %s", absPth, err)
+	}
+
+	if filter.Solution.Path == "" {
+		return Model{}, fmt.Errorf("solution filter (%s) does not define the base solution", absPth)
+	}
+
+	baseSolutionPth := filepath.Join(filepath.Dir(absPth), utility.FixWindowsPath(filter.Solution.Path))
+	baseSolutionDir := filepath.Dir(baseSolutionPth)
+
+	filteredPths := map[string]bool{}
+	for _, projectPth := range filter.Solution.Projects {
+		filteredPths[filepath.Join(baseSolutionDir, utility.FixWindowsPath(projectPth))] = true
+	}
+	foundPths := map[string]bool{}
+	allows := func(projectPth string) bool {
+		foundPths[projectPth] = true
+		return filteredPths[projectPth]
+	}
+
+	var solution Model
+	switch filepath.Ext(baseSolutionPth) {
+	case constants.SolutionExt:
+		solution, err = analyzeSolution(baseSolutionPth, analyzeProjects, allows)
+	case constants.SolutionXMLExt:
+		solution, err = analyzeXMLSolution(baseSolutionPth, analyzeProjects, allows)
+	default:
+		return Model{}, fmt.Errorf("solution filter (%s) refers to an unsupported base solution: %s", absPth, baseSolutionPth)
+	}
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to analyze base solution (%s) of solution filter (%s), error: %s", baseSolutionPth, absPth, err)
+	}
+
+	// msbuild fails to build a filter with projects missing from the base solution
+	missingPths := []string{}
+	for projectPth := range filteredPths {
+		if isProjectPth(projectPth) && !foundPths[projectPth] {
+			missingPths = append(missingPths, projectPth)
+		}
+	}
+	if len(missingPths) > 0 {
+		sort.Strings(missingPths)
+		return Model{}, fmt.Errorf("solution filter (%s) contains projects missing from its base solution (%s): %s", absPth, baseSolutionPth, strings.Join(missingPths, ", "))
+	}
+
+	// the filter is built by msbuild as a solution, containing only the filtered projects,
+	// the tools not supporting solution filters use the base solution and the filtered projects
+	fileName := filepath.Base(absPth)
+	solution.Pth = absPth
+	solution.BaseSolutionPth = baseSolutionPth
+	solution.Name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+
+	return solution, nil
+}
@@ -0,0 +1,90 @@
+package solution
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeSolutionFilter(t *testing.T) {
+	appID := generatedProjectID(1)
+	libID := generatedProjectID(2)
+
+	tests := []struct {
+		name           string
+		filterContent  string
+		wantProjectIDs []string
+		wantErr        string
+	}{
+		{
+			name: "filtered projects",
+			filterContent: `{
+  "solution": {
+    "path": "..\\Solution.slnx",
+    "projects": [
+      "App\\App.csproj"
+    ]
+  }
+}`,
+			wantProjectIDs: []string{appID},
+		},
+		{
+			name: "project missing from the base solution",
+			filterContent: `{
+  "solution": {
+    "path": "..\\Solution.slnx",
+    "projects": [
+      "App\\App.csproj",
+      "Missing\\Missing.csproj"
+    ]
+  }
+}`,
+			wantErr: filepath.Join("Missing", "Missing.csproj"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTestFiles(t, map[string]string{
+				"Solution.slnx":       xmlSolutionContent,
+				"App/App.csproj":      fmt.Sprintf(generatedProjectContent, appID, "App", ""),
+				"Lib/Lib.csproj":      fmt.Sprintf(generatedProjectContent, libID, "Lib", ""),
+				"Filters/Filter.slnf": tt.filterContent,
+			})
+			defer removeTestFiles(t, dir)
+
+			pth := filepath.Join(dir, "Filters", "Filter.slnf")
+			solution, err := New(pth, true)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want error containing %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %s", err)
+			}
+
+			if solution.Pth != pth || solution.Name != "Filter" {
+				t.Errorf("Pth, Name = %s, %s, want %s, Filter", solution.Pth, solution.Name, pth)
+			}
+			if want := filepath.Join(dir, "Solution.slnx"); solution.BaseSolutionPth != want {
+				t.Errorf("BaseSolutionPth = %s, want %s", solution.BaseSolutionPth, want)
+			}
+
+			projectIDs := []string{}
+			for projectID := range solution.ProjectMap {
+				projectIDs = append(projectIDs, projectID)
+			}
+			if !reflect.DeepEqual(projectIDs, tt.wantProjectIDs) {
+				t.Errorf("ProjectMap ids = %v, want %v", projectIDs, tt.wantProjectIDs)
+			}
+			// the dependency on the filtered out library is dropped
+			if len(solution.ProjectDependencies) != 0 {
+				t.Errorf("ProjectDependencies = %v, want none", solution.ProjectDependencies)
+			}
+		})
+	}
+}
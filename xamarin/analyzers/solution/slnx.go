@@ -0,0 +1,281 @@
+package solution
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// defaults of the .slnx format, if the solution does not list its build types or platforms
+var (
+	defaultXMLSolutionBuildTypes = []string{"Debug", "Release"}
+	defaultXMLSolutionPlatforms  = []string{"Any CPU"}
+)
+
+// xmlSolution is the root element of the .slnx solution format.
+type xmlSolution struct {
+	XMLName        xml.Name `xml:"Solution"`
+	Configurations struct {
+		BuildTypes []xmlSolutionName `xml:"BuildType"`
+		Platforms  []xmlSolutionName `xml:"Platform"`
+	} `xml:"Configurations"`
+	Folders []struct {
+		Name     string               `xml:"Name,attr"`
+		Projects []xmlSolutionProject `xml:"Project"`
+	} `xml:"Folder"`
+	Projects []xmlSolutionProject `xml:"Project"`
+}
+
+type xmlSolutionName struct {
+	Name string `xml:"Name,attr"`
+}
+
+// xmlSolutionProject is a project of the .slnx solution with its config mapping rules,
+// the Solution attribute of a rule is a Configuration|Platform pattern where * matches any value.
+type xmlSolutionProject struct {
+	Path              string                   `xml:"Path,attr"`
+	ID                string                   `xml:"Id,attr"`
+	BuildTypes        []xmlSolutionProjectRule `xml:"BuildType"`
+	Platforms         []xmlSolutionProjectRule `xml:"Platform"`
+	Builds            []xmlSolutionProjectRule `xml:"Build"`
+	BuildDependencies []struct {
+		Project string `xml:"Project,attr"`
+	} `xml:"BuildDependency"`
+
+	folder string // Name of the containing solution folder, like /src/Apps/
+}
+
+type xmlSolutionProjectRule struct {
+	Solution string `xml:"Solution,attr"`
+	Project  string `xml:"Project,attr"`
+}
+
+func analyzeXMLSolution(pth string, analyzeProjects bool, filter projectFilter) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("Failed to expand path (%s), error: %s", pth, err)
+	}
+
+	content, err := os.ReadFile(absPth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to read solution (%s), error: %s", absPth, err)
+	}
+
+	var parsedSolution xmlSolution
+	if err := xml.Unmarshal(content, &parsedSolution); err != nil {
+		return Model{}, fmt.Errorf("failed to parse solution (%s), error: %s", absPth, err)
+	}
+
+	solution := newModel(absPth)
+
+	buildTypes := names(parsedSolution.Configurations.BuildTypes, defaultXMLSolutionBuildTypes)
+	platforms := names(parsedSolution.Configurations.Platforms, defaultXMLSolutionPlatforms)
+
+	for _, buildType := range buildTypes {
+		for _, platform := range platforms {
+			config := utility.ToConfig(buildType, platform)
+			solution.ConfigMap[config] = config
+		}
+	}
+
+	xmlProjects := append([]xmlSolutionProject{}, parsedSolution.Projects...)
+	for _, folder := range parsedSolution.Folders {
+		folderID := addXMLSolutionFolder(&solution, folder.Name)
+
+		for _, xmlProject := range folder.Projects {
+			xmlProject.folder = folderID
+			xmlProjects = append(xmlProjects, xmlProject)
+		}
+	}
+
+	solutionDir := filepath.Dir(absPth)
+	projectRules := map[string]xmlSolutionProject{}
+
+	for _, xmlProject := range xmlProjects {
+		projectRelativePth := utility.FixWindowsPath(xmlProject.Path)
+		projectPth := filepath.Join(solutionDir, projectRelativePth)
+		if !isProjectPth(projectPth) || !filter.allows(projectPth) {
+			continue
+		}
+
+		projectID := xmlSolutionProjectID(xmlProject.ID, projectRelativePth)
+		fileName := filepath.Base(projectPth)
+
+		solution.ProjectMap[projectID] = project.Model{
+			ID:   projectID,
+			Name: strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+			Pth:  projectPth,
+
+			ConfigMap: map[string]string{},
+			Configs:   map[string]project.ConfigurationPlatformModel{},
+		}
+		projectRules[projectID] = xmlProject
+	}
+
+	if analyzeProjects {
+		if solution, err = analyzeSolutionProjects(solution); err != nil {
+			return Model{}, err
+		}
+
+		// project references refer to the ProjectGuid of the project file, prefer it over the path based id
+		projectMap := map[string]project.Model{}
+		for projectID, proj := range solution.ProjectMap {
+			rules := projectRules[projectID]
+			if rules.ID == "" && proj.ID != "" {
+				projectID = proj.ID
+				projectRules[projectID] = rules
+			}
+			projectMap[projectID] = proj
+		}
+		solution.ProjectMap = projectMap
+	}
+
+	projectIDByPth := map[string]string{}
+	for projectID, proj := range solution.ProjectMap {
+		projectIDByPth[proj.Pth] = projectID
+	}
+
+	for projectID := range solution.ProjectMap {
+		rules := projectRules[projectID]
+
+		if rules.folder != "" {
+			solution.NestedProjects[projectID] = rules.folder
+		}
+
+		for _, dependency := range rules.BuildDependencies {
+			dependencyPth := filepath.Join(solutionDir, utility.FixWindowsPath(dependency.Project))
+			if dependencyID, ok := projectIDByPth[dependencyPth]; ok {
+				solution.ProjectDependencies[projectID] = append(solution.ProjectDependencies[projectID], dependencyID)
+			}
+		}
+	}
+
+	// the project configs are known only after the project analysis,
+	// they are used to pick the default project platform
+	for projectID, proj := range solution.ProjectMap {
+		rules := projectRules[projectID]
+
+		for _, buildType := range buildTypes {
+			for _, platform := range platforms {
+				if build, ok := matchingProjectRule(rules.Builds, buildType, platform); ok && strings.EqualFold(build, "false") {
+					continue
+				}
+
+				projectBuildType := buildType
+				if mapped, ok := matchingProjectRule(rules.BuildTypes, buildType, platform); ok {
+					projectBuildType = mapped
+				}
+
+				projectPlatform := defaultProjectPlatform(proj, projectBuildType, platform)
+				if mapped, ok := matchingProjectRule(rules.Platforms, buildType, platform); ok {
+					projectPlatform = mapped
+				}
+				if projectPlatform == "Any CPU" {
+					projectPlatform = "AnyCPU"
+				}
+
+				proj.ConfigMap[utility.ToConfig(buildType, platform)] = utility.ToConfig(projectBuildType, projectPlatform)
+			}
+		}
+	}
+
+	return solution, nil
+}
+
+// addXMLSolutionFolder adds the solution folder given by its path (like /src/Apps/) and its parent folders,
+// and returns the folder's id.
+func addXMLSolutionFolder(solution *Model, folderPth string) string {
+	parentID := ""
+	currentPth := "/"
+
+	for _, name := range strings.Split(strings.Trim(folderPth, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		currentPth += name + "/"
+
+		folderID := pathProjectID(currentPth)
+		solution.Folders[folderID] = FolderModel{
+			ID:   folderID,
+			Name: name,
+		}
+		if parentID != "" {
+			solution.NestedProjects[folderID] = parentID
+		}
+
+		parentID = folderID
+	}
+
+	return parentID
+}
+
+func names(items []xmlSolutionName, defaults []string) []string {
+	if len(items) == 0 {
+		return defaults
+	}
+
+	values := []string{}
+	for _, item := range items {
+		values = append(values, item.Name)
+	}
+	return values
+}
+
+// xmlSolutionProjectID returns the project's Id from the solution,
+// or a stable GUID derived from the project path, as the .slnx format does not require project ids.
+func xmlSolutionProjectID(id, projectRelativePth string) string {
+	if id != "" {
+		return strings.ToUpper(strings.Trim(id, "{}"))
+	}
+
+	return pathProjectID(projectRelativePth)
+}
+
+// pathProjectID returns a stable GUID derived from the project path.
+func pathProjectID(projectPth string) string {
+	sum := md5.Sum([]byte(strings.ToLower(projectPth)))
+	hex := fmt.Sprintf("%X", sum)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", hex[0:8], hex[8:12], hex[12:16], hex[16:20], hex[20:32])
+}
+
+// matchingProjectRule returns the project value of the last rule matching the solution config.
+func matchingProjectRule(rules []xmlSolutionProjectRule, buildType, platform string) (string, bool) {
+	value, found := "", false
+
+	for _, rule := range rules {
+		split := strings.SplitN(rule.Solution, "|", 2)
+		ruleBuildType, rulePlatform := split[0], "*"
+		if len(split) == 2 {
+			rulePlatform = split[1]
+		}
+		if ruleBuildType == "" {
+			ruleBuildType = "*"
+		}
+
+		if (ruleBuildType == "*" || strings.EqualFold(ruleBuildType, buildType)) &&
+			(rulePlatform == "*" || utility.ConfigsEqual(rulePlatform, platform)) {
+			value, found = rule.Project, true
+		}
+	}
+
+	return value, found
+}
+
+// defaultProjectPlatform returns the solution platform if the project defines it, otherwise Any CPU.
+func defaultProjectPlatform(proj project.Model, buildType, platform string) string {
+	if len(proj.Configs) == 0 {
+		return platform
+	}
+
+	if _, ok := proj.Config(utility.ToConfig(buildType, platform)); ok {
+		return platform
+	}
+	return "AnyCPU"
+}
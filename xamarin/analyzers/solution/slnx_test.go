@@ -0,0 +1,119 @@
+package solution
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const xmlSolutionContent = `<Solution>
+  <Configurations>
+    <BuildType Name="Debug" />
+    <BuildType Name="Release" />
+    <Platform Name="iPhone" />
+  </Configurations>
+  <Folder Name="/src/">
+    <Project Path="App\App.csproj">
+      <BuildDependency Project="Lib\Lib.csproj" />
+    </Project>
+  </Folder>
+  <Project Path="Lib\Lib.csproj">
+    <Build Solution="Debug|*" Project="false" />
+  </Project>
+</Solution>
+`
+
+func TestAnalyzeXMLSolution(t *testing.T) {
+	appPathID := pathProjectID("App/App.csproj")
+	libPathID := pathProjectID("Lib/Lib.csproj")
+	appID := generatedProjectID(1)
+	libID := generatedProjectID(2)
+	folderID := pathProjectID("/src/")
+
+	tests := []struct {
+		name             string
+		analyzeProjects  bool
+		wantConfigMaps   map[string]map[string]string // Project ID - ConfigMap
+		wantDependencies map[string][]string
+		wantNested       map[string]string
+	}{
+		{
+			name: "path based ids",
+			wantConfigMaps: map[string]map[string]string{
+				appPathID: {"Debug|iPhone": "Debug|iPhone", "Release|iPhone": "Release|iPhone"},
+				libPathID: {"Release|iPhone": "Release|iPhone"},
+			},
+			wantDependencies: map[string][]string{appPathID: {libPathID}},
+			wantNested:       map[string]string{appPathID: folderID},
+		},
+		{
+			name:            "path based ids are replaced by the ProjectGuids",
+			analyzeProjects: true,
+			wantConfigMaps: map[string]map[string]string{
+				appID: {"Debug|iPhone": "Debug|AnyCPU", "Release|iPhone": "Release|iPhone"},
+				libID: {"Release|iPhone": "Release|iPhone"},
+			},
+			wantDependencies: map[string][]string{appID: {libID}},
+			wantNested:       map[string]string{appID: folderID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTestFiles(t, map[string]string{
+				"Solution.slnx":  xmlSolutionContent,
+				"App/App.csproj": fmt.Sprintf(generatedProjectContent, appID, "App", ""),
+				"Lib/Lib.csproj": fmt.Sprintf(generatedProjectContent, libID, "Lib", ""),
+			})
+			defer removeTestFiles(t, dir)
+
+			solution, err := New(filepath.Join(dir, "Solution.slnx"), tt.analyzeProjects)
+			if err != nil {
+				t.Fatalf("New() error = %s", err)
+			}
+
+			configMaps := map[string]map[string]string{}
+			for projectID, proj := range solution.ProjectMap {
+				configMaps[projectID] = proj.ConfigMap
+			}
+			if !reflect.DeepEqual(configMaps, tt.wantConfigMaps) {
+				t.Errorf("project ConfigMaps = %v, want %v", configMaps, tt.wantConfigMaps)
+			}
+			if !reflect.DeepEqual(solution.ProjectDependencies, tt.wantDependencies) {
+				t.Errorf("ProjectDependencies = %v, want %v", solution.ProjectDependencies, tt.wantDependencies)
+			}
+			if !reflect.DeepEqual(solution.NestedProjects, tt.wantNested) {
+				t.Errorf("NestedProjects = %v, want %v", solution.NestedProjects, tt.wantNested)
+			}
+		})
+	}
+}
+
+// writeTestFiles writes the files given by their slash separated relative paths into a temporary dir.
+func writeTestFiles(tb testing.TB, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "solution")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for relPth, content := range files {
+		pth := filepath.Join(dir, filepath.FromSlash(relPth))
+		if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+			tb.Fatal(err)
+		}
+		if err := ioutil.WriteFile(pth, []byte(content), 0600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func removeTestFiles(tb testing.TB, dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		tb.Log(err)
+	}
+}
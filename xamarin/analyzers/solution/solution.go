@@ -0,0 +1,470 @@
+package solution
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// The patterns of the classic .sln format's lines, matched against the trimmed lines.
+var (
+	solutionProjectRegexp               = regexp.MustCompile(`^Project\("{(?P<project_type_id>[^"]*)}"\) = "(?P<project_name>[^"]*)", "(?P<project_path>[^"]*)", "{(?P<project_id>[^"]*)}"`)
+	projectDependencyRegexp             = regexp.MustCompile(`^{(?P<dependency_id>[^}]*)} = {[^}]*}`)
+	solutionConfigurationPlatformRegexp = regexp.MustCompile(`(?P<config>[^|]*)\|(?P<platform>[^|]*) = (?P<m_config>[^|]*)\|(?P<m_platform>[^|]*)`)
+	projectConfigurationPlatformRegexp  = regexp.MustCompile(`{(?P<project_id>.*)}.(?P<config>.*)\|(?P<platform>.*)\.Build.* = (?P<mapped_config>.*)\|(?P<mapped_platform>.*)`)
+	nestedProjectRegexp                 = regexp.MustCompile(`^{(?P<child_id>[^}]*)} = {(?P<parent_id>[^}]*)}`)
+	solutionGUIDRegexp                  = regexp.MustCompile(`^SolutionGuid = {(?P<solution_guid>[^}]*)}`)
+)
+
+// The section markers of the classic .sln format.
+const (
+	projectStartPrefix                   = "Project("
+	projectEnd                           = "EndProject"
+	projectDependenciesSectionPrefix     = "ProjectSection(ProjectDependencies)"
+	projectSectionPrefix                 = "ProjectSection("
+	projectSectionEnd                    = "EndProjectSection"
+	solutionConfigurationPlatformsPrefix = "GlobalSection(SolutionConfigurationPlatforms)"
+	projectConfigurationPlatformsPrefix  = "GlobalSection(ProjectConfigurationPlatforms)"
+	nestedProjectsPrefix                 = "GlobalSection(NestedProjects)"
+	extensibilityGlobalsPrefix           = "GlobalSection(ExtensibilityGlobals)"
+	globalSectionPrefix                  = "GlobalSection("
+	globalSectionEnd                     = "EndGlobalSection"
+)
+
+// solutionSection is the section of the .sln file the parser is in.
+type solutionSection int
+
+const (
+	sectionNone solutionSection = iota
+	sectionProject
+	sectionProjectDependencies
+	sectionOtherProjectSection
+	sectionSolutionConfigurationPlatforms
+	sectionProjectConfigurationPlatforms
+	sectionNestedProjects
+	sectionExtensibilityGlobals
+	sectionOtherGlobalSection
+)
+
+// maxSolutionLineLength is the longest .sln line the parser accepts.
+const maxSolutionLineLength = 1024 * 1024
+
+// solutionFolderTypeGUID is the project type of the solution folders.
+const solutionFolderTypeGUID = "2150E333-8FDC-42A3-9474-1A3956D46DE8"
+
+// FolderModel ...
+type FolderModel struct {
+	ID   string
+	Name string
+}
+
+// Model ...
+type Model struct {
+	Pth  string
+	Name string
+	ID   string
+
+	BaseSolutionPth string // The solution (.sln, .slnx) a solution filter (.slnf) is based on, empty for the other solutions
+
+	ConfigMap map[string]string // Internal Configuartion|Platform - External Configuartion|Platform map
+
+	ProjectMap map[string]project.Model // Project ID - Project Model map
+
+	Folders             map[string]FolderModel // Solution folder ID - FolderModel map
+	NestedProjects      map[string]string      // Project or solution folder ID - parent solution folder ID map
+	ProjectDependencies map[string][]string    // Project ID - IDs of the projects to build before the project
+}
+
+func newModel(absPth string) Model {
+	fileName := filepath.Base(absPth)
+
+	return Model{
+		Pth:                 absPth,
+		Name:                strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		ConfigMap:           map[string]string{},
+		ProjectMap:          map[string]project.Model{},
+		Folders:             map[string]FolderModel{},
+		NestedProjects:      map[string]string{},
+		ProjectDependencies: map[string][]string{},
+	}
+}
+
+// New analyzes the given solution (.sln), XML solution (.slnx) or solution filter (.slnf).
+// A project file (.csproj, .fsproj) is analyzed as a solution containing only the project.
+func New(pth string, loadProjects bool) (Model, error) {
+	switch filepath.Ext(pth) {
+	case constants.CSProjExt, constants.FSProjExt:
+		return analyzeProjectAsSolution(pth)
+	case constants.SolutionXMLExt:
+		return analyzeXMLSolution(pth, loadProjects, nil)
+	case constants.SolutionFilterExt:
+		return analyzeSolutionFilter(pth, loadProjects)
+	}
+	return analyzeSolution(pth, loadProjects, nil)
+}
+
+// SolutionPth returns the solution the projects belong to: the base solution of a solution filter, otherwise the solution itself.
+func (solution Model) SolutionPth() string {
+	if solution.BaseSolutionPth != "" {
+		return solution.BaseSolutionPth
+	}
+	return solution.Pth
+}
+
+// ConfigList returns the solution configs in alphabetical order.
+func (solution Model) ConfigList() []string {
+	configList := []string{}
+	for config := range solution.ConfigMap {
+		configList = append(configList, config)
+	}
+	sort.Strings(configList)
+	return configList
+}
+
+// FolderPath returns the solution folder path of the given project or solution folder, like src/Apps.
+func (solution Model) FolderPath(id string) string {
+	names := []string{}
+	visited := map[string]bool{}
+
+	parentID, ok := solution.NestedProjects[id]
+	for ok && !visited[parentID] {
+		visited[parentID] = true
+
+		folder, found := solution.Folders[parentID]
+		if !found {
+			break
+		}
+		names = append([]string{folder.Name}, names...)
+
+		parentID, ok = solution.NestedProjects[parentID]
+	}
+
+	return strings.Join(names, "/")
+}
+
+// ResolveConfig returns the solution's Configuration and Platform matching the given ones,
+// see utility.NormalizeConfig for the matching rules.
+func (solution Model) ResolveConfig(configuration, platform string) (string, string, bool) {
+	config := utility.ToConfig(configuration, platform)
+	if _, ok := solution.ConfigMap[config]; ok {
+		return configuration, platform, true
+	}
+
+	for _, solutionConfig := range solution.ConfigList() {
+		if utility.ConfigsEqual(solutionConfig, config) {
+			split := strings.SplitN(solutionConfig, "|", 2)
+			return split[0], split[1], true
+		}
+	}
+
+	return "", "", false
+}
+
+// projectFilter reports whether the project at the given path is part of the analyzed solution, nil filter allows all projects.
+type projectFilter func(projectPth string) bool
+
+func (filter projectFilter) allows(projectPth string) bool {
+	return filter == nil || filter(projectPth)
+}
+
+func analyzeSolution(pth string, analyzeProjects bool, filter projectFilter) (Model, error) {
+	absPth, err := pathutil.AbsPath(pth)
+	if err != nil {
+		return Model{}, fmt.Errorf("Failed to expand path (%s), error: %s", pth, err)
+	}
+
+	file, err := os.Open(absPth)
+	if err != nil {
+		return Model{}, fmt.Errorf("failed to read solution (%s), error: %s", absPth, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logging.Warnf("Failed to close solution (%s), error: %s", absPth, err)
+		}
+	}()
+
+	parser := solutionParser{
+		solution:    newModel(absPth),
+		solutionDir: filepath.Dir(absPth),
+		filter:      filter,
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxSolutionLineLength)
+	for scanner.Scan() {
+		parser.parseLine(strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return Model{}, fmt.Errorf("failed to read solution (%s), error: %s", absPth, err)
+	}
+
+	if analyzeProjects {
+		return analyzeSolutionProjects(parser.solution)
+	}
+
+	return parser.solution, nil
+}
+
+// solutionParser reads the classic .sln format in a single pass, line by line,
+// the section of the current line decides which pattern is matched against it.
+type solutionParser struct {
+	solution    Model
+	solutionDir string
+	filter      projectFilter
+
+	section          solutionSection
+	currentProjectID string
+}
+
+func (parser *solutionParser) parseLine(line string) {
+	switch parser.section {
+	case sectionNone:
+		switch {
+		case strings.HasPrefix(line, projectStartPrefix):
+			parser.parseProject(line)
+			parser.section = sectionProject
+		case strings.HasPrefix(line, solutionConfigurationPlatformsPrefix):
+			parser.section = sectionSolutionConfigurationPlatforms
+		case strings.HasPrefix(line, projectConfigurationPlatformsPrefix):
+			parser.section = sectionProjectConfigurationPlatforms
+		case strings.HasPrefix(line, nestedProjectsPrefix):
+			parser.section = sectionNestedProjects
+		case strings.HasPrefix(line, extensibilityGlobalsPrefix):
+			parser.section = sectionExtensibilityGlobals
+		case strings.HasPrefix(line, globalSectionPrefix):
+			parser.section = sectionOtherGlobalSection
+		}
+	case sectionProject:
+		switch {
+		case line == projectEnd:
+			parser.section = sectionNone
+			parser.currentProjectID = ""
+		case strings.HasPrefix(line, projectDependenciesSectionPrefix):
+			parser.section = sectionProjectDependencies
+		case strings.HasPrefix(line, projectSectionPrefix):
+			parser.section = sectionOtherProjectSection
+		}
+	case sectionProjectDependencies, sectionOtherProjectSection:
+		if line == projectSectionEnd {
+			parser.section = sectionProject
+			return
+		}
+		if parser.section == sectionProjectDependencies {
+			parser.parseProjectDependency(line)
+		}
+	default:
+		if line == globalSectionEnd {
+			parser.section = sectionNone
+			return
+		}
+
+		switch parser.section {
+		case sectionSolutionConfigurationPlatforms:
+			parser.parseSolutionConfigurationPlatform(line)
+		case sectionProjectConfigurationPlatforms:
+			parser.parseProjectConfigurationPlatform(line)
+		case sectionNestedProjects:
+			parser.parseNestedProject(line)
+		case sectionExtensibilityGlobals:
+			parser.parseSolutionGUID(line)
+		}
+	}
+}
+
+// Project("{project type id}") = "name", "path", "{project id}"
+func (parser *solutionParser) parseProject(line string) {
+	matches := solutionProjectRegexp.FindStringSubmatch(line)
+	if len(matches) != 5 {
+		return
+	}
+
+	projectTypeID := strings.ToUpper(matches[1])
+	projectName := matches[2]
+	projectID := strings.ToUpper(matches[4])
+	projectRelativePth := utility.FixWindowsPath(matches[3])
+	projectPth := filepath.Join(parser.solutionDir, projectRelativePth)
+
+	if isProjectPth(projectPth) && parser.filter.allows(projectPth) {
+		parser.solution.ProjectMap[projectID] = project.Model{
+			ID:   projectID,
+			Name: projectName,
+			Pth:  projectPth,
+
+			ConfigMap: map[string]string{},
+			Configs:   map[string]project.ConfigurationPlatformModel{},
+		}
+	}
+
+	if projectTypeID == solutionFolderTypeGUID {
+		parser.solution.Folders[projectID] = FolderModel{
+			ID:   projectID,
+			Name: projectName,
+		}
+	}
+
+	parser.currentProjectID = projectID
+}
+
+// {dependency id} = {dependency id}
+func (parser *solutionParser) parseProjectDependency(line string) {
+	matches := projectDependencyRegexp.FindStringSubmatch(line)
+	if len(matches) != 2 || parser.currentProjectID == "" {
+		return
+	}
+
+	dependencyID := strings.ToUpper(matches[1])
+	parser.solution.ProjectDependencies[parser.currentProjectID] = append(parser.solution.ProjectDependencies[parser.currentProjectID], dependencyID)
+}
+
+// Configuration|Platform = Configuration|Platform
+func (parser *solutionParser) parseSolutionConfigurationPlatform(line string) {
+	matches := solutionConfigurationPlatformRegexp.FindStringSubmatch(line)
+	if len(matches) != 5 {
+		return
+	}
+
+	configuration := matches[1]
+	platform := matches[2]
+
+	mappedConfiguration := matches[3]
+	mappedPlatform := matches[4]
+
+	parser.solution.ConfigMap[utility.ToConfig(configuration, platform)] = utility.ToConfig(mappedConfiguration, mappedPlatform)
+}
+
+// {project id}.Configuration|Platform.Build.0 = Configuration|Platform
+func (parser *solutionParser) parseProjectConfigurationPlatform(line string) {
+	matches := projectConfigurationPlatformRegexp.FindStringSubmatch(line)
+	if len(matches) != 6 {
+		return
+	}
+
+	projectID := strings.ToUpper(matches[1])
+
+	project, found := parser.solution.ProjectMap[projectID]
+	if !found {
+		return
+	}
+
+	solutionConfiguration := matches[2]
+	solutionPlatform := matches[3]
+	projectConfiguration := matches[4]
+	projectPlatform := matches[5]
+	if projectPlatform == "Any CPU" {
+		projectPlatform = "AnyCPU"
+	}
+
+	project.ConfigMap[utility.ToConfig(solutionConfiguration, solutionPlatform)] = utility.ToConfig(projectConfiguration, projectPlatform)
+}
+
+// {child id} = {parent solution folder id}
+func (parser *solutionParser) parseNestedProject(line string) {
+	matches := nestedProjectRegexp.FindStringSubmatch(line)
+	if len(matches) != 3 {
+		return
+	}
+
+	parser.solution.NestedProjects[strings.ToUpper(matches[1])] = strings.ToUpper(matches[2])
+}
+
+// SolutionGuid = {solution id}
+func (parser *solutionParser) parseSolutionGUID(line string) {
+	matches := solutionGUIDRegexp.FindStringSubmatch(line)
+	if len(matches) != 2 {
+		return
+	}
+
+	parser.solution.ID = strings.ToUpper(matches[1])
+}
+
+// analyzeSolutionProjects replaces the solution's projects with their analyzed definitions,
+// keeping the project names, paths and config mappings read from the solution.
+// The projects are analyzed concurrently.
+func analyzeSolutionProjects(solution Model) (Model, error) {
+	type analyzeResult struct {
+		projectID string
+		project   project.Model
+		err       error
+	}
+
+	projectIDs := []string{}
+	for projectID := range solution.ProjectMap {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Strings(projectIDs)
+
+	workers := runtime.NumCPU()
+	if workers > len(projectIDs) {
+		workers = len(projectIDs)
+	}
+
+	projectIDChan := make(chan string)
+	resultChan := make(chan analyzeResult, len(projectIDs))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for projectID := range projectIDChan {
+				projectDefinition, err := project.New(solution.ProjectMap[projectID].Pth)
+				resultChan <- analyzeResult{projectID: projectID, project: projectDefinition, err: err}
+			}
+		}()
+	}
+
+	for _, projectID := range projectIDs {
+		projectIDChan <- projectID
+	}
+	close(projectIDChan)
+
+	wg.Wait()
+	close(resultChan)
+
+	results := map[string]analyzeResult{}
+	for result := range resultChan {
+		results[result.projectID] = result
+	}
+
+	projectMap := map[string]project.Model{}
+
+	// report the failure of the first project in a stable order
+	for _, projectID := range projectIDs {
+		proj := solution.ProjectMap[projectID]
+		result := results[projectID]
+		if result.err != nil {
+			return Model{}, fmt.Errorf("failed to analyze project (%s), error: %s", proj.Pth, result.err)
+		}
+
+		projectDefinition := result.project
+		projectDefinition.Name = proj.Name
+		projectDefinition.Pth = proj.Pth
+		projectDefinition.ConfigMap = proj.ConfigMap
+
+		projectMap[projectID] = projectDefinition
+	}
+
+	solution.ProjectMap = projectMap
+
+	return solution, nil
+}
+
+// isProjectPth reports whether the solution item is a project supported by the analyzer.
+func isProjectPth(pth string) bool {
+	return strings.HasSuffix(pth, constants.CSProjExt) ||
+		strings.HasSuffix(pth, constants.SHProjExt) ||
+		strings.HasSuffix(pth, constants.FSProjExt)
+}
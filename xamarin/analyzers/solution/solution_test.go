@@ -0,0 +1,516 @@
+package solution
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+)
+
+const nestedSolutionContent = `
+Microsoft Visual Studio Solution File, Format Version 12.00
+# Visual Studio Version 16
+VisualStudioVersion = 16.0.29709.97
+MinimumVisualStudioVersion = 10.0.40219.1
+Project("{2150E333-8FDC-42A3-9474-1A3956D46DE8}") = "src", "src", "{0A1B2C3D-0000-0000-0000-000000000001}"
+EndProject
+Project("{2150E333-8FDC-42A3-9474-1A3956D46DE8}") = "Apps", "Apps", "{0A1B2C3D-0000-0000-0000-000000000002}"
+	ProjectSection(SolutionItems) = preProject
+		README.md = README.md
+	EndProjectSection
+EndProject
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "App.iOS", "src\Apps\App.iOS\App.iOS.csproj", "{a0000000-0000-0000-0000-000000000001}"
+	ProjectSection(ProjectDependencies) = postProject
+		{B0000000-0000-0000-0000-000000000001} = {B0000000-0000-0000-0000-000000000001}
+	EndProjectSection
+EndProject
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Core", "src\Core\Core.csproj", "{B0000000-0000-0000-0000-000000000001}"
+EndProject
+Project("{D954291E-2A0B-460D-934E-DC6B0785DB48}") = "Shared", "src\Shared\Shared.shproj", "{C0000000-0000-0000-0000-000000000001}"
+EndProject
+Global
+	GlobalSection(SharedMSBuildProjectFiles) = preSolution
+		src\Shared\Shared.projitems*{c0000000-0000-0000-0000-000000000001}*SharedItemsImports = 13
+	EndGlobalSection
+	GlobalSection(SolutionConfigurationPlatforms) = preSolution
+		Debug|iPhone = Debug|iPhone
+		Release|iPhone = Release|iPhone
+	EndGlobalSection
+	GlobalSection(ProjectConfigurationPlatforms) = postSolution
+		{A0000000-0000-0000-0000-000000000001}.Debug|iPhone.ActiveCfg = Debug|iPhone
+		{A0000000-0000-0000-0000-000000000001}.Debug|iPhone.Build.0 = Debug|iPhone
+		{A0000000-0000-0000-0000-000000000001}.Release|iPhone.ActiveCfg = Release|iPhone
+		{A0000000-0000-0000-0000-000000000001}.Release|iPhone.Build.0 = Release|iPhone
+		{B0000000-0000-0000-0000-000000000001}.Debug|iPhone.ActiveCfg = Debug|Any CPU
+		{B0000000-0000-0000-0000-000000000001}.Debug|iPhone.Build.0 = Debug|Any CPU
+		{B0000000-0000-0000-0000-000000000001}.Release|iPhone.ActiveCfg = Release|Any CPU
+		{B0000000-0000-0000-0000-000000000001}.Release|iPhone.Build.0 = Release|Any CPU
+	EndGlobalSection
+	GlobalSection(SolutionProperties) = preSolution
+		HideSolutionNode = FALSE
+	EndGlobalSection
+	GlobalSection(NestedProjects) = preSolution
+		{0A1B2C3D-0000-0000-0000-000000000002} = {0A1B2C3D-0000-0000-0000-000000000001}
+		{A0000000-0000-0000-0000-000000000001} = {0A1B2C3D-0000-0000-0000-000000000002}
+		{B0000000-0000-0000-0000-000000000001} = {0A1B2C3D-0000-0000-0000-000000000001}
+		{C0000000-0000-0000-0000-000000000001} = {0A1B2C3D-0000-0000-0000-000000000001}
+	EndGlobalSection
+	GlobalSection(ExtensibilityGlobals) = postSolution
+		SolutionGuid = {d0000000-0000-0000-0000-000000000001}
+	EndGlobalSection
+EndGlobal
+`
+
+func TestAnalyzeSolution(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solution")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	newProject := func(id, name, relativePth string, configMap map[string]string) project.Model {
+		return project.Model{
+			ID:        id,
+			Name:      name,
+			Pth:       filepath.Join(dir, relativePth),
+			ConfigMap: configMap,
+			Configs:   map[string]project.ConfigurationPlatformModel{},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    Model
+	}{
+		{
+			name:    "mac",
+			content: macTestSolutionContent,
+			want: Model{
+				ConfigMap: map[string]string{
+					"Debug|Any CPU":   "Debug|Any CPU",
+					"Release|Any CPU": "Release|Any CPU",
+				},
+				ProjectMap: map[string]project.Model{
+					"4DA5EAC6-6F80-4FEC-AF81-194210F10B51": newProject("4DA5EAC6-6F80-4FEC-AF81-194210F10B51", "Hello_Mac", "Hello_Mac/Hello_Mac.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+				},
+				Folders:             map[string]FolderModel{},
+				NestedProjects:      map[string]string{},
+				ProjectDependencies: map[string][]string{},
+			},
+		},
+		{
+			name:    "lower case project ids",
+			content: macIDTestSolutionContent,
+			want: Model{
+				ConfigMap: map[string]string{
+					"Debug|Any CPU":   "Debug|Any CPU",
+					"Release|Any CPU": "Release|Any CPU",
+				},
+				ProjectMap: map[string]project.Model{
+					"4DA5EAC6-6F80-4FEC-AF81-194210F10B51": newProject("4DA5EAC6-6F80-4FEC-AF81-194210F10B51", "Hello_Mac", "Hello_Mac/Hello_Mac.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+				},
+				Folders:             map[string]FolderModel{},
+				NestedProjects:      map[string]string{},
+				ProjectDependencies: map[string][]string{},
+			},
+		},
+		{
+			name:    "tvOS",
+			content: tvTestSolutionContent,
+			want: Model{
+				ConfigMap: map[string]string{
+					"Debug|iPhoneSimulator":   "Debug|iPhoneSimulator",
+					"Release|iPhone":          "Release|iPhone",
+					"Release|iPhoneSimulator": "Release|iPhoneSimulator",
+					"Debug|iPhone":            "Debug|iPhone",
+				},
+				ProjectMap: map[string]project.Model{
+					"51D9C362-2997-4029-B38F-06C36F17056E": newProject("51D9C362-2997-4029-B38F-06C36F17056E", "tvos", "tvos/tvos.csproj", map[string]string{
+						"Debug|iPhoneSimulator":   "Debug|iPhoneSimulator",
+						"Release|iPhone":          "Release|iPhone",
+						"Release|iPhoneSimulator": "Release|iPhoneSimulator",
+						"Debug|iPhone":            "Debug|iPhone",
+					}),
+				},
+				Folders:             map[string]FolderModel{},
+				NestedProjects:      map[string]string{},
+				ProjectDependencies: map[string][]string{},
+			},
+		},
+		{
+			name:    "android",
+			content: androidTestSolutionContent,
+			want: Model{
+				ConfigMap: map[string]string{
+					"Debug|Any CPU":   "Debug|Any CPU",
+					"Release|Any CPU": "Release|Any CPU",
+				},
+				ProjectMap: map[string]project.Model{
+					"9D1D32A3-D13F-4F23-B7D4-EF9D52B06E60": newProject("9D1D32A3-D13F-4F23-B7D4-EF9D52B06E60", "CreditCardValidator.Droid", "CreditCardValidator.Droid/CreditCardValidator.Droid.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+					"048C57FD-A3A8-41E5-94B6-C41C3B4F5D95": newProject("048C57FD-A3A8-41E5-94B6-C41C3B4F5D95", "CreditCardValidator.Droid.UITests", "CreditCardValidator.Droid.UITests/CreditCardValidator.Droid.UITests.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+					"99A825A6-6F99-4B94-9F65-E908A6347F1E": newProject("99A825A6-6F99-4B94-9F65-E908A6347F1E", "CreditCardValidator", "CreditCardValidator/CreditCardValidator.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+					"EF586485-1B11-4873-9D60-FFDBCBFE7E99": newProject("EF586485-1B11-4873-9D60-FFDBCBFE7E99", "CreditCardValidator.Droid.NunitTests", "CreditCardValidator.Droid.NunitTests/CreditCardValidator.Droid.NunitTests.csproj", map[string]string{
+						"Debug|Any CPU":   "Debug|AnyCPU",
+						"Release|Any CPU": "Release|AnyCPU",
+					}),
+				},
+				Folders:             map[string]FolderModel{},
+				NestedProjects:      map[string]string{},
+				ProjectDependencies: map[string][]string{},
+			},
+		},
+		{
+			name:    "solution folders, nested projects and project dependencies",
+			content: nestedSolutionContent,
+			want: Model{
+				ID: "D0000000-0000-0000-0000-000000000001",
+				ConfigMap: map[string]string{
+					"Debug|iPhone":   "Debug|iPhone",
+					"Release|iPhone": "Release|iPhone",
+				},
+				ProjectMap: map[string]project.Model{
+					"A0000000-0000-0000-0000-000000000001": newProject("A0000000-0000-0000-0000-000000000001", "App.iOS", "src/Apps/App.iOS/App.iOS.csproj", map[string]string{
+						"Debug|iPhone":   "Debug|iPhone",
+						"Release|iPhone": "Release|iPhone",
+					}),
+					"B0000000-0000-0000-0000-000000000001": newProject("B0000000-0000-0000-0000-000000000001", "Core", "src/Core/Core.csproj", map[string]string{
+						"Debug|iPhone":   "Debug|AnyCPU",
+						"Release|iPhone": "Release|AnyCPU",
+					}),
+					"C0000000-0000-0000-0000-000000000001": newProject("C0000000-0000-0000-0000-000000000001", "Shared", "src/Shared/Shared.shproj", map[string]string{}),
+				},
+				Folders: map[string]FolderModel{
+					"0A1B2C3D-0000-0000-0000-000000000001": {ID: "0A1B2C3D-0000-0000-0000-000000000001", Name: "src"},
+					"0A1B2C3D-0000-0000-0000-000000000002": {ID: "0A1B2C3D-0000-0000-0000-000000000002", Name: "Apps"},
+				},
+				NestedProjects: map[string]string{
+					"0A1B2C3D-0000-0000-0000-000000000002": "0A1B2C3D-0000-0000-0000-000000000001",
+					"A0000000-0000-0000-0000-000000000001": "0A1B2C3D-0000-0000-0000-000000000002",
+					"B0000000-0000-0000-0000-000000000001": "0A1B2C3D-0000-0000-0000-000000000001",
+					"C0000000-0000-0000-0000-000000000001": "0A1B2C3D-0000-0000-0000-000000000001",
+				},
+				ProjectDependencies: map[string][]string{
+					"A0000000-0000-0000-0000-000000000001": {"B0000000-0000-0000-0000-000000000001"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(dir, "Solution.sln")
+			if err := ioutil.WriteFile(pth, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := analyzeSolution(pth, false, nil)
+			if err != nil {
+				t.Fatalf("analyzeSolution() error = %s", err)
+			}
+
+			want := tt.want
+			want.Pth = pth
+			want.Name = "Solution"
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("analyzeSolution() =\n%#v\nwant\n%#v", got, want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSolutionFolderPath(t *testing.T) {
+	pth := writeTestSolution(t, nestedSolutionContent)
+	defer removeTestSolution(t, pth)
+
+	solution, err := analyzeSolution(pth, false, nil)
+	if err != nil {
+		t.Fatalf("analyzeSolution() error = %s", err)
+	}
+
+	tests := map[string]string{
+		"A0000000-0000-0000-0000-000000000001": "src/Apps",
+		"B0000000-0000-0000-0000-000000000001": "src",
+		"0A1B2C3D-0000-0000-0000-000000000001": "",
+	}
+	for id, want := range tests {
+		if got := solution.FolderPath(id); got != want {
+			t.Errorf("FolderPath(%s) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestAnalyzeGeneratedSolution(t *testing.T) {
+	const projectCount = 500
+
+	pth := writeTestSolution(t, generateSolutionContent(projectCount))
+	defer removeTestSolution(t, pth)
+
+	solution, err := analyzeSolution(pth, false, nil)
+	if err != nil {
+		t.Fatalf("analyzeSolution() error = %s", err)
+	}
+
+	if len(solution.ProjectMap) != projectCount {
+		t.Fatalf("analyzeSolution() projects = %d, want %d", len(solution.ProjectMap), projectCount)
+	}
+	if len(solution.ConfigMap) != 4 {
+		t.Errorf("analyzeSolution() configs = %v, want 4", solution.ConfigMap)
+	}
+
+	for i := 0; i < projectCount; i++ {
+		id := generatedProjectID(i)
+
+		proj, ok := solution.ProjectMap[id]
+		if !ok {
+			t.Fatalf("analyzeSolution() project %s not found", id)
+		}
+		if want := fmt.Sprintf("Project%03d", i); proj.Name != want {
+			t.Errorf("project %s name = %s, want %s", id, proj.Name, want)
+		}
+		if want := "Release|iPhone"; proj.ConfigMap["Release|iPhone"] != want {
+			t.Errorf("project %s config map = %v, want Release|iPhone: %s", id, proj.ConfigMap, want)
+		}
+		if got, want := solution.FolderPath(id), fmt.Sprintf("Folder%02d", i%10); got != want {
+			t.Errorf("project %s folder path = %s, want %s", id, got, want)
+		}
+		if i > 0 {
+			if got, want := solution.ProjectDependencies[id], []string{generatedProjectID(i - 1)}; !reflect.DeepEqual(got, want) {
+				t.Errorf("project %s dependencies = %v, want %v", id, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkParseSolution(b *testing.B) {
+	pth := writeTestSolution(b, generateSolutionContent(500))
+	defer removeTestSolution(b, pth)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := analyzeSolution(pth, false, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAnalyzeSolutionProjects(t *testing.T) {
+	const projectCount = 100
+
+	pth := writeGeneratedSolution(t, projectCount, nil)
+	defer removeTestSolution(t, pth)
+
+	solution, err := analyzeSolution(pth, true, nil)
+	if err != nil {
+		t.Fatalf("analyzeSolution() error = %s", err)
+	}
+
+	if len(solution.ProjectMap) != projectCount {
+		t.Fatalf("analyzeSolution() projects = %d, want %d", len(solution.ProjectMap), projectCount)
+	}
+
+	for i := 0; i < projectCount; i++ {
+		id := generatedProjectID(i)
+		name := fmt.Sprintf("Project%03d", i)
+
+		proj, ok := solution.ProjectMap[id]
+		if !ok {
+			t.Fatalf("analyzeSolution() project %s not found", id)
+		}
+
+		// the name, the path and the config map come from the solution, the rest from the project file
+		if proj.Name != name {
+			t.Errorf("project %s name = %s, want %s", id, proj.Name, name)
+		}
+		if want := filepath.Join(filepath.Dir(pth), fmt.Sprintf("Folder%02d", i%10), name, name+".csproj"); proj.Pth != want {
+			t.Errorf("project %s path = %s, want %s", id, proj.Pth, want)
+		}
+		if got, want := proj.ConfigMap["Release|iPhone"], "Release|iPhone"; got != want {
+			t.Errorf("project %s config map = %v, want Release|iPhone: %s", id, proj.ConfigMap, want)
+		}
+		if proj.ID != id {
+			t.Errorf("project %s id = %s", id, proj.ID)
+		}
+		if proj.SDK != constants.SDKIOS || proj.OutputType != "exe" || proj.AssemblyName != name+"App" {
+			t.Errorf("project %s = SDK %s, output type %s, assembly %s", id, proj.SDK, proj.OutputType, proj.AssemblyName)
+		}
+		if config, ok := proj.Config("Release|iPhone"); !ok || !reflect.DeepEqual(config.MtouchArchs, []string{"ARM64"}) {
+			t.Errorf("project %s Release|iPhone config = %+v", id, config)
+		}
+
+		var wantReferences []string
+		if i > 0 {
+			wantReferences = []string{generatedProjectID(i - 1)}
+		}
+		if !reflect.DeepEqual(proj.ReferredProjectIDs, wantReferences) {
+			t.Errorf("project %s references = %v, want %v", id, proj.ReferredProjectIDs, wantReferences)
+		}
+	}
+}
+
+func TestAnalyzeSolutionProjectsError(t *testing.T) {
+	// of the failing projects the first one in project id order is reported, whichever worker finishes first
+	pth := writeGeneratedSolution(t, 100, map[int]bool{37: true, 81: true})
+	defer removeTestSolution(t, pth)
+
+	for i := 0; i < 10; i++ {
+		_, err := analyzeSolution(pth, true, nil)
+		if err == nil {
+			t.Fatalf("analyzeSolution() error = nil, want error")
+		}
+		if want := filepath.Join("Project037", "Project037.csproj"); !strings.Contains(err.Error(), want) {
+			t.Fatalf("analyzeSolution() error = %s, want the error of %s", err, want)
+		}
+	}
+}
+
+func BenchmarkAnalyzeSolutionProjects(b *testing.B) {
+	pth := writeGeneratedSolution(b, 500, nil)
+	defer removeTestSolution(b, pth)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := analyzeSolution(pth, true, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func writeTestSolution(tb testing.TB, content string) string {
+	dir, err := ioutil.TempDir("", "solution")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	pth := filepath.Join(dir, "Solution.sln")
+	if err := ioutil.WriteFile(pth, []byte(content), 0600); err != nil {
+		tb.Fatal(err)
+	}
+	return pth
+}
+
+func removeTestSolution(tb testing.TB, pth string) {
+	if err := os.RemoveAll(filepath.Dir(pth)); err != nil {
+		tb.Log(err)
+	}
+}
+
+const generatedProjectContent = `<?xml version="1.0" encoding="utf-8"?>
+<Project DefaultTargets="Build" ToolsVersion="4.0" xmlns="http://schemas.microsoft.com/developer/msbuild/2003">
+  <PropertyGroup>
+    <ProjectTypeGuids>{FEACFBD2-3405-455C-9665-78FE426C6842};{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}</ProjectTypeGuids>
+    <ProjectGuid>{%s}</ProjectGuid>
+    <OutputType>Exe</OutputType>
+    <AssemblyName>%sApp</AssemblyName>
+  </PropertyGroup>
+  <PropertyGroup Condition=" '$(Configuration)|$(Platform)' == 'Release|iPhone' ">
+    <OutputPath>bin\iPhone\Release</OutputPath>
+    <MtouchArch>ARM64</MtouchArch>
+  </PropertyGroup>
+%s</Project>
+`
+
+// writeGeneratedSolution writes the generated solution and its project files, each project refers to the previous one.
+// The projects of the given indexes are not valid XML.
+func writeGeneratedSolution(tb testing.TB, projectCount int, invalidProjects map[int]bool) string {
+	pth := writeTestSolution(tb, generateSolutionContent(projectCount))
+
+	for i := 0; i < projectCount; i++ {
+		name := fmt.Sprintf("Project%03d", i)
+		projectDir := filepath.Join(filepath.Dir(pth), fmt.Sprintf("Folder%02d", i%10), name)
+		if err := os.MkdirAll(projectDir, 0700); err != nil {
+			tb.Fatal(err)
+		}
+
+		reference := ""
+		if i > 0 {
+			previousName := fmt.Sprintf("Project%03d", i-1)
+			reference = fmt.Sprintf("  <ItemGroup>\n    <ProjectReference Include=\"..\\..\\Folder%02d\\%s\\%s.csproj\">\n      <Project>{%s}</Project>\n    </ProjectReference>\n  </ItemGroup>\n", (i-1)%10, previousName, previousName, generatedProjectID(i-1))
+		}
+
+		content := fmt.Sprintf(generatedProjectContent, generatedProjectID(i), name, reference)
+		if invalidProjects[i] {
+			content = "<Project"
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(projectDir, name+".csproj"), []byte(content), 0600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return pth
+}
+
+func generatedProjectID(i int) string {
+	return fmt.Sprintf("A0000000-0000-0000-0000-%012d", i)
+}
+
+func generatedFolderID(i int) string {
+	return fmt.Sprintf("F0000000-0000-0000-0000-%012d", i)
+}
+
+// generateSolutionContent generates a Visual Studio solution of iOS projects spread over 10 solution folders,
+// each project depends on the previous one.
+func generateSolutionContent(projectCount int) string {
+	solutionConfigs := []string{"Debug|iPhone", "Release|iPhone", "Debug|iPhoneSimulator", "Release|iPhoneSimulator"}
+
+	var content strings.Builder
+	content.WriteString("\nMicrosoft Visual Studio Solution File, Format Version 12.00\n# Visual Studio Version 16\n")
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&content, "Project(\"{%s}\") = \"Folder%02d\", \"Folder%02d\", \"{%s}\"\nEndProject\n", solutionFolderTypeGUID, i, i, generatedFolderID(i))
+	}
+
+	for i := 0; i < projectCount; i++ {
+		fmt.Fprintf(&content, "Project(\"{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}\") = \"Project%03d\", \"Folder%02d\\Project%03d\\Project%03d.csproj\", \"{%s}\"\n", i, i%10, i, i, generatedProjectID(i))
+		if i > 0 {
+			fmt.Fprintf(&content, "\tProjectSection(ProjectDependencies) = postProject\n\t\t{%s} = {%s}\n\tEndProjectSection\n", generatedProjectID(i-1), generatedProjectID(i-1))
+		}
+		content.WriteString("EndProject\n")
+	}
+
+	content.WriteString("Global\n\tGlobalSection(SolutionConfigurationPlatforms) = preSolution\n")
+	for _, config := range solutionConfigs {
+		fmt.Fprintf(&content, "\t\t%s = %s\n", config, config)
+	}
+	content.WriteString("\tEndGlobalSection\n\tGlobalSection(ProjectConfigurationPlatforms) = postSolution\n")
+	for i := 0; i < projectCount; i++ {
+		for _, config := range solutionConfigs {
+			fmt.Fprintf(&content, "\t\t{%s}.%s.ActiveCfg = %s\n", generatedProjectID(i), config, config)
+			fmt.Fprintf(&content, "\t\t{%s}.%s.Build.0 = %s\n", generatedProjectID(i), config, config)
+		}
+	}
+	content.WriteString("\tEndGlobalSection\n\tGlobalSection(NestedProjects) = preSolution\n")
+	for i := 0; i < projectCount; i++ {
+		fmt.Fprintf(&content, "\t\t{%s} = {%s}\n", generatedProjectID(i), generatedFolderID(i%10))
+	}
+	content.WriteString("\tEndGlobalSection\n\tGlobalSection(ExtensibilityGlobals) = postSolution\n\t\tSolutionGuid = {D0000000-0000-0000-0000-000000000001}\n\tEndGlobalSection\nEndGlobal\n")
+
+	return content.String()
+}
@@ -0,0 +1,861 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/nunit"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// Model ...
+type Model struct {
+	solution      solution.Model
+	embedderByPth map[string]project.Model // Embedded project path - embedding project map, like app extension - app
+
+	projectTypeWhitelist []constants.SDK
+	buildTool            buildtools.BuildTool
+
+	outWriter io.Writer
+	errWriter io.Writer
+
+	commandTimeout time.Duration
+
+	testResultDir string
+
+	androidPackageFormat constants.AndroidPackageFormat
+
+	codesignSettings CodesignSettings
+
+	archiveDir string
+}
+
+// CodesignSettings are the code signing properties of the iOS and tvOS builds,
+// empty values are left to the projects.
+type CodesignSettings struct {
+	Key             string // CodesignKey: signing identity or its prefix
+	Provision       string // CodesignProvision: provisioning profile name or UUID
+	EntitlementsPth string // CodesignEntitlements
+}
+
+// SetOutputs ...
+func (builder *Model) SetOutputs(outWriter, errWriter io.Writer) {
+	builder.outWriter = outWriter
+	builder.errWriter = errWriter
+}
+
+// SetCommandTimeout sets the time limit of a single build command, zero value means no time limit.
+// The time limit of a whole build is the deadline of the context the build methods are called with.
+func (builder *Model) SetCommandTimeout(timeout time.Duration) {
+	builder.commandTimeout = timeout
+}
+
+// SetTestResultDir sets the directory where the NUnit test projects write their results,
+// into a <test project name>.xml file each.
+func (builder *Model) SetTestResultDir(dir string) {
+	builder.testResultDir = dir
+}
+
+// SetAndroidPackageFormat sets the package formats of the Android builds,
+// AndroidPackageFormatProject leaves them to the projects.
+func (builder *Model) SetAndroidPackageFormat(format constants.AndroidPackageFormat) {
+	builder.androidPackageFormat = format
+}
+
+// SetCodesignSettings sets the code signing properties of the iOS and tvOS builds.
+func (builder *Model) SetCodesignSettings(settings CodesignSettings) {
+	builder.codesignSettings = settings
+}
+
+// SetArchiveDir sets the directory the Apple projects' xcarchives are created in and collected from,
+// empty value means the Xcode archives dir (~/Library/Developer/Xcode/Archives).
+func (builder *Model) SetArchiveDir(dir string) {
+	builder.archiveDir = dir
+}
+
+// archivesDir returns the directory of the xcarchives.
+func (builder Model) archivesDir() (string, error) {
+	if builder.archiveDir != "" {
+		return builder.archiveDir, nil
+	}
+	return xcodeArchivesDir()
+}
+
+// runCommand runs the command within the command timeout and the deadline of the context, whichever is sooner.
+func (builder Model) runCommand(ctx context.Context, command tools.Runnable, projectName string) error {
+	if ctx.Err() != nil {
+		err := tools.ContextError(ctx, command.String())
+		var timeoutErr *tools.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			timeoutErr.ProjectName = projectName
+		}
+		return err
+	}
+
+	if timeoutable, ok := command.(tools.Timeoutable); ok {
+		timeoutable.SetTimeout(builder.commandTimeout)
+	}
+
+	var err error
+	if contextRunnable, ok := command.(tools.ContextRunnable); ok {
+		err = contextRunnable.RunWithContext(ctx, builder.outWriter, builder.errWriter)
+	} else {
+		err = command.Run(builder.outWriter, builder.errWriter)
+	}
+	var timeoutErr *tools.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		timeoutErr.ProjectName = projectName
+	}
+	return err
+}
+
+// OutputModel ...
+type OutputModel struct {
+	Pth        string
+	OutputType constants.OutputType
+	ABI        string // Android ABI of the per-ABI APKs
+}
+
+// ProjectOutputModel ...
+type ProjectOutputModel struct {
+	ProjectType constants.SDK
+	Outputs     []OutputModel
+}
+
+// ProjectOutputMap ...
+type ProjectOutputMap map[string]ProjectOutputModel // Project Name - ProjectOutputModel
+
+// TestProjectOutputModel ...
+type TestProjectOutputModel struct {
+	TestFramwork         constants.TestFramework
+	ReferredProjectNames []string
+	Output               OutputModel
+}
+
+// TestProjectOutputMap ...
+type TestProjectOutputMap map[string]TestProjectOutputModel // Test Project Name - TestProjectOutputModel
+
+// PrepareCommandCallback ...
+type PrepareCommandCallback func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, command *tools.Editable)
+
+// BuildCommandCallback ...
+type BuildCommandCallback func(solutionName string, projectName string, sdk constants.SDK, testFramework constants.TestFramework, commandStr string, alreadyPerformed bool)
+
+// ClearCommandCallback ...
+type ClearCommandCallback func(project project.Model, dir string)
+
+// New ...
+func New(solutionPth string, projectTypeWhitelist []constants.SDK, buildTool buildtools.BuildTool) (Model, error) {
+	if err := validateSolutionPth(solutionPth); err != nil {
+		return Model{}, err
+	}
+
+	solution, err := solution.New(solutionPth, true)
+	if err != nil {
+		return Model{}, err
+	}
+
+	if projectTypeWhitelist == nil {
+		projectTypeWhitelist = []constants.SDK{}
+	}
+
+	return Model{
+		solution:      solution,
+		embedderByPth: embedders(solution),
+
+		projectTypeWhitelist: projectTypeWhitelist,
+		buildTool:            buildTool,
+	}, nil
+}
+
+// CleanAll ...
+func (builder Model) CleanAll(callback ClearCommandCallback) error {
+	whitelistedProjects := builder.whitelistedProjects()
+
+	for _, proj := range whitelistedProjects {
+
+		projectDir := filepath.Dir(proj.Pth)
+
+		{
+			binPth := filepath.Join(projectDir, "bin")
+			if exist, err := pathutil.IsDirExists(binPth); err != nil {
+				return err
+			} else if exist {
+				if callback != nil {
+					callback(proj, binPth)
+				}
+
+				if err := os.RemoveAll(binPth); err != nil {
+					return err
+				}
+			}
+		}
+
+		{
+			objPth := filepath.Join(projectDir, "obj")
+			if exist, err := pathutil.IsDirExists(objPth); err != nil {
+				return err
+			} else if exist {
+				if callback != nil {
+					callback(proj, objPth)
+				}
+
+				if err := os.RemoveAll(objPth); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// CleanXcodeArchives removes the xcarchives of the whitelisted Apple projects from the archives dir.
+func (builder Model) CleanXcodeArchives(callback ClearCommandCallback) error {
+	archivesDir, err := builder.archivesDir()
+	if err != nil {
+		return err
+	}
+
+	if exist, err := pathutil.IsDirExists(archivesDir); err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	for _, proj := range builder.whitelistedProjects() {
+		if proj.SDK != constants.SDKIOS && proj.SDK != constants.SDKTvOS && proj.SDK != constants.SDKMacOS && proj.SDK != constants.SDKMacCatalyst {
+			continue
+		}
+		if proj.AssemblyName == "" {
+			continue
+		}
+
+		// archives are named like "<name> 10-19-26 1.02 PM.xcarchive" (Xcode adds a comma after the date),
+		// a bare prefix match would remove the archives of other apps sharing the name's prefix
+		xcarchivePattern := regexp.MustCompile(fmt.Sprintf(`^%s \d{1,2}-\d{1,2}-\d{2},? .*\.xcarchive$`, regexp.QuoteMeta(proj.AssemblyName)))
+
+		var xcarchivePths []string
+		if err := filepath.Walk(archivesDir, func(pth string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() && xcarchivePattern.MatchString(info.Name()) {
+				xcarchivePths = append(xcarchivePths, pth)
+				return filepath.SkipDir
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, pth := range xcarchivePths {
+			if callback != nil {
+				callback(proj, pth)
+			}
+
+			if err := os.RemoveAll(pth); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// CleanSolution ...
+func (builder Model) CleanSolution(configuration, platform string, callback BuildCommandCallback) error {
+	return builder.CleanSolutionWithContext(context.Background(), configuration, platform, callback)
+}
+
+// CleanSolutionWithContext runs the Clean target on the solution.
+func (builder Model) CleanSolutionWithContext(ctx context.Context, configuration, platform string, callback BuildCommandCallback) error {
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return err
+	}
+
+	cleanCommands, err := builder.solutionCommands("Clean", configuration, platform)
+	if err != nil {
+		return fmt.Errorf("Failed to create clean command, error: %s", err)
+	}
+
+	for _, cleanCommand := range cleanCommands {
+		// Callback to notify the caller about next running command
+		if callback != nil {
+			callback(builder.solution.Name, "", constants.SDKUnknown, constants.TestFrameworkUnknown, cleanCommand.String(), false)
+		}
+
+		if err := builder.runCommand(ctx, cleanCommand, builder.solution.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BuildSolution ...
+func (builder Model) BuildSolution(configuration, platform string, callback BuildCommandCallback) error {
+	return builder.BuildSolutionWithContext(context.Background(), configuration, platform, callback)
+}
+
+// BuildSolutionWithContext ...
+func (builder Model) BuildSolutionWithContext(ctx context.Context, configuration, platform string, callback BuildCommandCallback) error {
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return err
+	}
+
+	buildCommands, err := builder.solutionCommands("Build", configuration, platform)
+	if err != nil {
+		return fmt.Errorf("Failed to create build command, error: %s", err)
+	}
+
+	for _, buildCommand := range buildCommands {
+		// Callback to notify the caller about next running command
+		if callback != nil {
+			callback(builder.solution.Name, "", constants.SDKUnknown, constants.TestFrameworkUnknown, buildCommand.String(), false)
+		}
+
+		if err := builder.runCommand(ctx, buildCommand, builder.solution.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BuildAllProjects ...
+func (builder Model) BuildAllProjects(configuration, platform string, buildIpa bool, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	return builder.BuildAllProjectsWithContext(context.Background(), configuration, platform, buildIpa, prepareCallback, callback)
+}
+
+// BuildAllProjectsWithContext ...
+func (builder Model) BuildAllProjectsWithContext(ctx context.Context, configuration, platform string, buildIpa bool, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	warnings := []string{}
+
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return warnings, err
+	}
+
+	buildableProjects, warns := builder.buildableProjects(configuration, platform)
+	if len(buildableProjects) == 0 {
+		return warns, fmt.Errorf("No project to build found")
+	}
+
+	perfomedCommands := []tools.Printable{}
+
+	for _, proj := range buildableProjects {
+		buildCommands, warns, err := builder.buildProjectCommand(configuration, platform, proj, buildIpa)
+		warnings = append(warnings, warns...)
+		if err != nil {
+			return warnings, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		for _, buildCommand := range buildCommands {
+			// Callback to let the caller to modify the command
+			if prepareCallback != nil {
+				editabeCommand := tools.Editable(buildCommand)
+				prepareCallback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, &editabeCommand)
+			}
+
+			// Check if same command was already performed
+			alreadyPerformed := false
+			if tools.PrintableSliceContains(perfomedCommands, buildCommand) {
+				alreadyPerformed = true
+			}
+
+			// Callback to notify the caller about next running command
+			if callback != nil {
+				callback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, buildCommand.String(), alreadyPerformed)
+			}
+
+			if !alreadyPerformed {
+				if err := builder.runCommand(ctx, buildCommand, proj.Name); err != nil {
+					return warnings, err
+				}
+				perfomedCommands = append(perfomedCommands, buildCommand)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// BuildAllUITestableXamarinProjects ...
+func (builder Model) BuildAllUITestableXamarinProjects(configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	return builder.BuildAllUITestableXamarinProjectsWithContext(context.Background(), configuration, platform, prepareCallback, callback)
+}
+
+// BuildAllUITestableXamarinProjectsWithContext ...
+func (builder Model) BuildAllUITestableXamarinProjectsWithContext(ctx context.Context, configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	warnings := []string{}
+
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return warnings, err
+	}
+
+	if err := builder.BuildSolutionWithContext(ctx, configuration, platform, callback); err != nil {
+		return nil, err
+	}
+
+	_, buildableReferredProjects, warns := builder.buildableXamarinUITestProjectsAndReferredProjects(configuration, platform)
+	if len(buildableReferredProjects) == 0 {
+		return warns, fmt.Errorf("No project to build found")
+	}
+
+	perfomedCommands := []tools.Printable{}
+
+	for _, proj := range buildableReferredProjects {
+		buildCommands, warns, err := builder.buildProjectCommand(configuration, platform, proj, true)
+		warnings = append(warnings, warns...)
+		if err != nil {
+			return warnings, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		for _, buildCommand := range buildCommands {
+			// Callback to let the caller to modify the command
+			if prepareCallback != nil {
+				editabeCommand := tools.Editable(buildCommand)
+				prepareCallback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, &editabeCommand)
+			}
+
+			// Check if same command was already performed
+			alreadyPerformed := false
+			if tools.PrintableSliceContains(perfomedCommands, buildCommand) {
+				alreadyPerformed = true
+			}
+
+			// Callback to notify the caller about next running command
+			if callback != nil {
+				callback(builder.solution.Name, proj.Name, proj.SDK, proj.TestFramework, buildCommand.String(), alreadyPerformed)
+			}
+
+			if !alreadyPerformed {
+				if err := builder.runCommand(ctx, buildCommand, proj.Name); err != nil {
+					return warnings, err
+				}
+				perfomedCommands = append(perfomedCommands, buildCommand)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// BuildAllXamarinUITestProjects ...
+func (builder Model) BuildAllXamarinUITestProjects(configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	return builder.BuildAllXamarinUITestProjectsWithContext(context.Background(), configuration, platform, prepareCallback, callback)
+}
+
+// BuildAllXamarinUITestProjectsWithContext builds the Xamarin.UITest projects referring to the buildable projects,
+// it does not run the tests: they run on devices, with the test assemblies and the archived apps.
+func (builder Model) BuildAllXamarinUITestProjectsWithContext(ctx context.Context, configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	warnings := []string{}
+
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return warnings, err
+	}
+
+	buildableTestProjects, _, warns := builder.buildableXamarinUITestProjectsAndReferredProjects(configuration, platform)
+	if len(buildableTestProjects) == 0 {
+		return warns, fmt.Errorf("No project to build found")
+	}
+
+	perfomedCommands := []tools.Printable{}
+
+	for _, testProj := range buildableTestProjects {
+		buildCommand, warns, err := builder.buildXamarinUITestProjectCommand(configuration, platform, testProj)
+		warnings = append(warnings, warns...)
+		if err != nil {
+			return warnings, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		// Callback to let the caller to modify the command
+		if prepareCallback != nil {
+			editabeCommand := tools.Editable(buildCommand)
+			prepareCallback(builder.solution.Name, testProj.Name, testProj.SDK, testProj.TestFramework, &editabeCommand)
+		}
+
+		// Check if same command was already performed
+		alreadyPerformed := false
+		if tools.PrintableSliceContains(perfomedCommands, buildCommand) {
+			alreadyPerformed = true
+		}
+
+		// Callback to notify the caller about next running command
+		if callback != nil {
+			callback(builder.solution.Name, testProj.Name, testProj.SDK, testProj.TestFramework, buildCommand.String(), alreadyPerformed)
+		}
+
+		if !alreadyPerformed {
+			if err := builder.runCommand(ctx, buildCommand, testProj.Name); err != nil {
+				return warnings, err
+			}
+			perfomedCommands = append(perfomedCommands, buildCommand)
+		}
+	}
+
+	return warnings, nil
+}
+
+// BuildAllXamarinUITestAndReferredProjects ...
+func (builder Model) BuildAllXamarinUITestAndReferredProjects(configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	return builder.BuildAllXamarinUITestAndReferredProjectsWithContext(context.Background(), configuration, platform, prepareCallback, callback)
+}
+
+// BuildAllXamarinUITestAndReferredProjectsWithContext builds the projects tested by Xamarin.UITest, then the test projects.
+func (builder Model) BuildAllXamarinUITestAndReferredProjectsWithContext(ctx context.Context, configuration, platform string, prepareCallback PrepareCommandCallback, callback BuildCommandCallback) ([]string, error) {
+	warnings := []string{}
+
+	buildWarnings, err := builder.BuildAllUITestableXamarinProjectsWithContext(ctx, configuration, platform, prepareCallback, callback)
+	warnings = append(warnings, buildWarnings...)
+	if err != nil {
+		return warnings, err
+	}
+
+	testBuildWarnings, err := builder.BuildAllXamarinUITestProjectsWithContext(ctx, configuration, platform, prepareCallback, callback)
+	warnings = append(warnings, testBuildWarnings...)
+	if err != nil {
+		return warnings, err
+	}
+
+	return warnings, nil
+}
+
+// RunAllNunitTestProjects ...
+func (builder Model) RunAllNunitTestProjects(configuration, platform string, callback BuildCommandCallback, prepareCallback PrepareCommandCallback) ([]string, error) {
+	return builder.RunAllNunitTestProjectsWithContext(context.Background(), configuration, platform, callback, prepareCallback)
+}
+
+// RunAllNunitTestProjectsWithContext ...
+func (builder Model) RunAllNunitTestProjectsWithContext(ctx context.Context, configuration, platform string, callback BuildCommandCallback, prepareCallback PrepareCommandCallback) ([]string, error) {
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	buildableProjects, warns := builder.buildableNunitTestProjects(configuration, platform)
+	if len(buildableProjects) == 0 {
+		return warns, fmt.Errorf("No project to build found")
+	}
+
+	nunitConsolePth, err := nunit.Nunit3ConsolePath(filepath.Dir(builder.solution.SolutionPth()))
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []string{}
+	perfomedCommands := []tools.Printable{}
+
+	for _, testProj := range buildableProjects {
+		buildCommand, warns, err := builder.buildNunitTestProjectCommand(configuration, platform, testProj, nunitConsolePth)
+		warnings = append(warnings, warns...)
+		if err != nil {
+			return warnings, fmt.Errorf("Failed to create build command, error: %s", err)
+		}
+
+		// Callback to let the caller to modify the command
+		if prepareCallback != nil {
+			editabeCommand := tools.Editable(buildCommand)
+			prepareCallback(builder.solution.Name, testProj.Name, constants.SDKUnknown, constants.TestFrameworkNunitTest, &editabeCommand)
+		}
+
+		// Check if same command was already performed
+		alreadyPerformed := false
+		if tools.PrintableSliceContains(perfomedCommands, buildCommand) {
+			alreadyPerformed = true
+		}
+
+		// Callback to notify the caller about next running command
+		if callback != nil {
+			callback(builder.solution.Name, testProj.Name, constants.SDKUnknown, constants.TestFrameworkNunitTest, buildCommand.String(), alreadyPerformed)
+		}
+
+		if !alreadyPerformed {
+			if err := builder.runCommand(ctx, buildCommand, testProj.Name); err != nil {
+				return warnings, err
+			}
+			perfomedCommands = append(perfomedCommands, buildCommand)
+		}
+	}
+
+	return warnings, nil
+}
+
+// BuildAndRunAllNunitTestProjects ...
+func (builder Model) BuildAndRunAllNunitTestProjects(configuration, platform string, callback BuildCommandCallback, prepareCallback PrepareCommandCallback) ([]string, error) {
+	return builder.BuildAndRunAllNunitTestProjectsWithContext(context.Background(), configuration, platform, callback, prepareCallback)
+}
+
+// BuildAndRunAllNunitTestProjectsWithContext ...
+func (builder Model) BuildAndRunAllNunitTestProjectsWithContext(ctx context.Context, configuration, platform string, callback BuildCommandCallback, prepareCallback PrepareCommandCallback) ([]string, error) {
+	if err := builder.BuildSolutionWithContext(ctx, configuration, platform, callback); err != nil {
+		return nil, err
+	}
+
+	return builder.RunAllNunitTestProjectsWithContext(ctx, configuration, platform, callback, prepareCallback)
+}
+
+// CollectProjectOutputs ...
+func (builder Model) CollectProjectOutputs(configuration, platform string, startTime, endTime time.Time) (ProjectOutputMap, error) {
+	projectOutputMap := ProjectOutputMap{}
+
+	buildableProjects, _ := builder.buildableProjects(configuration, platform)
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	archivesDir, err := builder.archivesDir()
+	if err != nil {
+		return ProjectOutputMap{}, err
+	}
+
+	for _, proj := range buildableProjects {
+		projectConfigKey, ok := proj.MappedConfig(solutionConfig)
+		if !ok {
+			continue
+		}
+
+		projectConfig, ok := proj.Config(projectConfigKey)
+		if !ok {
+			continue
+		}
+
+		projectOutputs, ok := projectOutputMap[proj.Name]
+		if !ok {
+			projectOutputs = ProjectOutputModel{
+				ProjectType: proj.SDK,
+				Outputs:     []OutputModel{},
+			}
+		}
+
+		switch proj.SDK {
+		case constants.SDKIOS, constants.SDKTvOS:
+			if IsDeviceArch(projectConfig.MtouchArchs...) {
+				xcarchivePth, err := exportLatestXCArchive(archivesDir, proj.AssemblyName, startTime, endTime)
+				if err != nil {
+					return ProjectOutputMap{}, err
+				} else if xcarchivePth != "" {
+					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+						Pth:        xcarchivePth,
+						OutputType: constants.OutputTypeXCArchive,
+					})
+				} else {
+					logging.Debugf("No valid xcarchive path found.")
+				}
+
+				ipaPth, err := exportIpa(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime)
+				if err != nil {
+					return ProjectOutputMap{}, err
+				}
+
+				// the project configuration may not build an ipa (BuildIpa disabled), package it from the archive
+				if ipaPth == "" && xcarchivePth != "" {
+					logging.Debugf("No valid IPA path found, packaging it from the xcarchive.")
+
+					// the ipa is an extra of the archive, failing to package it does not fail the output collection
+					if ipaPth, err = exportIpaFromXCArchive(xcarchivePth, projectConfig.OutputDir, proj.AssemblyName); err != nil {
+						logging.Warnf("Failed to package the IPA of project (%s) from the xcarchive, error: %s", proj.Name, err)
+						ipaPth = ""
+					}
+				}
+
+				if ipaPth != "" {
+					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+						Pth:        ipaPth,
+						OutputType: constants.OutputTypeIPA,
+					})
+				} else {
+					logging.Debugf("No valid IPA path found.")
+				}
+
+				if dsymPth, err := exportAppDSYM(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
+					return ProjectOutputMap{}, err
+				} else if dsymPth != "" {
+					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+						Pth:        dsymPth,
+						OutputType: constants.OutputTypeDSYM,
+					})
+				} else {
+					logging.Debugf("No valid dsym path found.")
+				}
+			}
+
+			if appPth, err := exportApp(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, err
+			} else if appPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        appPth,
+					OutputType: constants.OutputTypeAPP,
+				})
+			} else {
+				logging.Debugf("No valid app path found.")
+			}
+		case constants.SDKMacOS, constants.SDKMacCatalyst:
+			if appPth, err := exportApp(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, err
+			} else if appPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        appPth,
+					OutputType: constants.OutputTypeAPP,
+				})
+			} else {
+				logging.Debugf("No valid app path found.")
+			}
+
+			if pkgPth, err := exportPKG(projectConfig.OutputDir, proj.AssemblyName, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, err
+			} else if pkgPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        pkgPth,
+					OutputType: constants.OutputTypePKG,
+				})
+			} else {
+				logging.Debugf("No valid pkg path found.")
+			}
+		case constants.SDKAndroid:
+			packageName, err := androidPackageName(proj.ManifestPth)
+			if err != nil {
+				return ProjectOutputMap{}, fmt.Errorf("could get package name from manifest file at %v. Error: %v", proj.ManifestPth, err)
+			}
+
+			perABIApks := map[string]string{}
+			if projectConfig.AndroidPackagePerABI && len(projectConfig.AndroidABIs) > 0 {
+				if perABIApks, err = exportPerABIApks(projectConfig.OutputDir, packageName, projectConfig.AndroidABIs, startTime, endTime); err != nil {
+					return ProjectOutputMap{}, fmt.Errorf("could not export per-ABI apks. Error: %v", err)
+				}
+			}
+
+			for _, abi := range projectConfig.AndroidABIs {
+				if apkPth, ok := perABIApks[abi]; ok {
+					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+						Pth:        apkPth,
+						OutputType: constants.OutputTypeAPK,
+						ABI:        abi,
+					})
+				}
+			}
+
+			// the per-ABI apks replace the single apk
+			if len(perABIApks) == 0 {
+				if apkPth, err := exportApk(projectConfig.OutputDir, packageName, startTime, endTime); err != nil {
+					return ProjectOutputMap{}, fmt.Errorf("could not export apk. Error: %v", err)
+				} else if apkPth != "" {
+					projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+						Pth:        apkPth,
+						OutputType: constants.OutputTypeAPK,
+					})
+				} else {
+					logging.Debugf("No valid apk path found.")
+				}
+			}
+
+			if aabPth, err := exportAab(projectConfig.OutputDir, packageName, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, fmt.Errorf("could not export aab. Error: %v", err)
+			} else if aabPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        aabPth,
+					OutputType: constants.OutputTypeAAB,
+				})
+			} else {
+				logging.Debugf("No valid aab path found.")
+			}
+
+			projectDir := filepath.Dir(proj.Pth)
+
+			if mappingPth, err := exportMapping(projectDir, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, fmt.Errorf("could not export mapping. Error: %v", err)
+			} else if mappingPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        mappingPth,
+					OutputType: constants.OutputTypeMapping,
+				})
+			} else {
+				logging.Debugf("No valid mapping path found.")
+			}
+
+			if symbolsPth, err := exportNativeSymbols(projectDir, startTime, endTime); err != nil {
+				return ProjectOutputMap{}, fmt.Errorf("could not export native symbols. Error: %v", err)
+			} else if symbolsPth != "" {
+				projectOutputs.Outputs = append(projectOutputs.Outputs, OutputModel{
+					Pth:        symbolsPth,
+					OutputType: constants.OutputTypeNativeSymbols,
+				})
+			} else {
+				logging.Debugf("No valid native symbols path found.")
+			}
+		}
+
+		if len(projectOutputs.Outputs) > 0 {
+			projectOutputMap[proj.Name] = projectOutputs
+		}
+	}
+
+	return projectOutputMap, nil
+}
+
+// CollectXamarinUITestProjectOutputs ...
+func (builder Model) CollectXamarinUITestProjectOutputs(configuration, platform string, startTime, endTime time.Time) (TestProjectOutputMap, []string, error) {
+	testProjectOutputMap := TestProjectOutputMap{}
+	warnings := []string{}
+
+	buildableTestProjects, _, _ := builder.buildableXamarinUITestProjectsAndReferredProjects(configuration, platform)
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, testProj := range buildableTestProjects {
+		projectConfigKey, ok := testProj.MappedConfig(solutionConfig)
+		if !ok {
+			continue
+		}
+
+		projectConfig, ok := testProj.Config(projectConfigKey)
+		if !ok {
+			continue
+		}
+
+		if dllPth, err := exportDLL(projectConfig.OutputDir, testProj.AssemblyName, startTime, endTime); err != nil {
+			return TestProjectOutputMap{}, warnings, err
+		} else if dllPth != "" {
+			referredProjectNames := []string{}
+			referredProjectIDs := testProj.ReferredProjectIDs
+			for _, referredProjectID := range referredProjectIDs {
+				referredProject, ok := builder.solution.ProjectMap[referredProjectID]
+				if !ok {
+					warnings = append(warnings, fmt.Sprintf("project reference exist with project id: %s, but project not found in solution", referredProjectID))
+				}
+
+				referredProjectNames = append(referredProjectNames, referredProject.Name)
+			}
+
+			testProjectOutputMap[testProj.Name] = TestProjectOutputModel{
+				TestFramwork:         testProj.TestFramework,
+				ReferredProjectNames: referredProjectNames,
+				Output: OutputModel{
+					Pth:        dllPth,
+					OutputType: constants.OutputTypeDLL,
+				},
+			}
+		}
+	}
+
+	return testProjectOutputMap, warnings, nil
+}
@@ -0,0 +1,272 @@
+package builder
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools/msbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools/xbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/nunit"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// solutionCommand returns the msbuild or xbuild command running on the solution in the solution config.
+// xbuild does not support solution filters, for a solution filter it runs on the given project of the base solution,
+// in the project config the solution config is mapped to.
+func (builder Model) solutionCommand(configuration, platform string, proj project.Model) (*xbuild.Model, error) {
+	var command *xbuild.Model
+	var err error
+
+	if builder.buildTool == buildtools.Msbuild {
+		command, err = msbuild.New(builder.solution.Pth, "")
+		if err == nil {
+			command.SetSolutionDir(filepath.Dir(builder.solution.SolutionPth()))
+		}
+	} else if builder.solution.BaseSolutionPth == "" {
+		command, err = xbuild.New(builder.solution.Pth, "")
+	} else {
+		projectConfig, _ := mappedProjectConfig(proj, configuration, platform)
+		configuration, platform = projectConfig.Configuration, projectConfig.Platform
+
+		command, err = xbuild.New(builder.solution.BaseSolutionPth, proj.Pth)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	command.SetConfiguration(configuration)
+	command.SetPlatform(platform)
+
+	return command, nil
+}
+
+// solutionCommands returns the commands running the target on the solution,
+// or on each project of a solution filter having the solution config, if the solution command runs on a project.
+func (builder Model) solutionCommands(target, configuration, platform string) ([]tools.Runnable, error) {
+	if builder.buildTool == buildtools.Msbuild || builder.solution.BaseSolutionPth == "" {
+		command, err := builder.solutionCommand(configuration, platform, project.Model{})
+		if err != nil {
+			return nil, err
+		}
+
+		command.SetTarget(target)
+		return []tools.Runnable{command}, nil
+	}
+
+	commands := []tools.Runnable{}
+	for _, proj := range builder.solutionProjects() {
+		if _, ok := mappedProjectConfig(proj, configuration, platform); !ok {
+			continue
+		}
+
+		command, err := builder.solutionCommand(configuration, platform, proj)
+		if err != nil {
+			return nil, err
+		}
+
+		command.SetTarget(target)
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+// mappedProjectConfig returns the project config the solution config is mapped to.
+func mappedProjectConfig(proj project.Model, configuration, platform string) (project.ConfigurationPlatformModel, bool) {
+	projectConfigKey, ok := proj.MappedConfig(utility.ToConfig(configuration, platform))
+	if !ok {
+		return project.ConfigurationPlatformModel{}, false
+	}
+
+	return proj.Config(projectConfigKey)
+}
+
+func (builder Model) buildProjectCommand(configuration, platform string, proj project.Model, buildIpa bool) ([]tools.Runnable, []string, error) {
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	projectConfigKey, ok := proj.MappedConfig(solutionConfig)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+	}
+
+	projectConfig, ok := proj.Config(projectConfigKey)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) contains mapping for solution config (%s), but does not have project configuration", proj.Name, solutionConfig))
+	}
+
+	// Prepare build commands
+	buildCommands := []tools.Runnable{}
+
+	switch proj.SDK {
+	case constants.SDKIOS, constants.SDKTvOS:
+		command, err := builder.solutionCommand(configuration, platform, proj)
+		if err != nil {
+			return []tools.Runnable{}, warnings, err
+		}
+
+		command.SetTarget("Build")
+		command.SetArchiveOnBuild(true)
+		command.SetArchivePath(builder.archiveDir)
+
+		if IsDeviceArch(projectConfig.MtouchArchs...) && buildIpa {
+			command.SetBuildIpa(true)
+		}
+
+		command.SetCodesignKey(builder.codesignSettings.Key)
+		command.SetCodesignProvision(builder.codesignSettings.Provision)
+		command.SetCodesignEntitlements(builder.codesignSettings.EntitlementsPth)
+
+		buildCommands = append(buildCommands, command)
+	case constants.SDKMacOS:
+		command, err := builder.solutionCommand(configuration, platform, proj)
+		if err != nil {
+			return []tools.Runnable{}, warnings, err
+		}
+
+		command.SetTarget("Build")
+		command.SetArchiveOnBuild(true)
+		command.SetArchivePath(builder.archiveDir)
+
+		buildCommands = append(buildCommands, command)
+	case constants.SDKMacCatalyst:
+		var command *xbuild.Model
+		var err error
+
+		if builder.buildTool == buildtools.Msbuild {
+			command, err = msbuild.New(builder.solution.SolutionPth(), proj.Pth)
+		} else {
+			command, err = xbuild.New(builder.solution.SolutionPth(), proj.Pth)
+		}
+
+		if err != nil {
+			return []tools.Runnable{}, warnings, err
+		}
+
+		command.SetTarget("Build")
+		command.SetConfiguration(projectConfig.Configuration)
+		command.SetArchiveOnBuild(true)
+		command.SetArchivePath(builder.archiveDir)
+		command.SetCreatePackage(true)
+
+		// multi-targeting projects build every target framework by default
+		if len(proj.TargetFrameworks) > 1 {
+			command.SetTargetFramework(macCatalystTargetFramework(proj.TargetFrameworks))
+		}
+
+		buildCommands = append(buildCommands, command)
+	case constants.SDKAndroid:
+		var command *xbuild.Model
+		var err error
+
+		if builder.buildTool == buildtools.Msbuild {
+			command, err = msbuild.New(builder.solution.SolutionPth(), proj.Pth)
+		} else {
+			command, err = xbuild.New(builder.solution.SolutionPth(), proj.Pth)
+		}
+
+		if err != nil {
+			return []tools.Runnable{}, warnings, err
+		}
+
+		if projectConfig.SignAndroid {
+			command.SetTarget("SignAndroidPackage")
+		} else {
+			command.SetTarget("PackageForAndroid")
+		}
+
+		command.SetConfiguration(projectConfig.Configuration)
+
+		if !isPlatformAnyCPU(projectConfig.Platform) {
+			command.SetPlatform(projectConfig.Platform)
+		}
+
+		switch builder.androidPackageFormat {
+		case constants.AndroidPackageFormatAPK, constants.AndroidPackageFormatAAB:
+			command.SetAndroidPackageFormats(string(builder.androidPackageFormat))
+		case constants.AndroidPackageFormatBoth:
+			if proj.SDKStyle {
+				command.SetAndroidPackageFormats(string(constants.AndroidPackageFormatAAB), string(constants.AndroidPackageFormatAPK))
+			} else {
+				// Xamarin.Android builds a single package format at once
+				apkCommand := *command
+				apkCommand.SetAndroidPackageFormats(string(constants.AndroidPackageFormatAPK))
+				buildCommands = append(buildCommands, &apkCommand)
+
+				command.SetAndroidPackageFormats(string(constants.AndroidPackageFormatAAB))
+			}
+		}
+
+		buildCommands = append(buildCommands, command)
+	}
+
+	return buildCommands, warnings, nil
+}
+
+func (builder Model) buildXamarinUITestProjectCommand(configuration, platform string, proj project.Model) (tools.Runnable, []string, error) {
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	projectConfigKey, ok := proj.MappedConfig(solutionConfig)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+	}
+
+	projectConfig, ok := proj.Config(projectConfigKey)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) contains mapping for solution config (%s), but does not have project configuration", proj.Name, solutionConfig))
+	}
+
+	var command *xbuild.Model
+	var err error
+
+	if builder.buildTool == buildtools.Msbuild {
+		command, err = msbuild.New(builder.solution.SolutionPth(), proj.Pth)
+	} else {
+		command, err = xbuild.New(builder.solution.SolutionPth(), proj.Pth)
+	}
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	command.SetConfiguration(projectConfig.Configuration)
+
+	return command, warnings, nil
+}
+
+func (builder Model) buildNunitTestProjectCommand(configuration, platform string, proj project.Model, nunitConsolePth string) (tools.Runnable, []string, error) {
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	projectConfigKey, ok := proj.MappedConfig(solutionConfig)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+	}
+
+	projectConfig, ok := proj.Config(projectConfigKey)
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("project (%s) contains mapping for solution config (%s), but does not have project configuration", proj.Name, solutionConfig))
+	}
+
+	command, err := nunit.New(nunitConsolePth)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	command.SetProjectPth(proj.Pth)
+	command.SetConfig(projectConfig.Configuration)
+
+	if builder.testResultDir != "" {
+		command.SetResultLogPth(filepath.Join(builder.testResultDir, proj.Name+".xml"))
+	}
+
+	return command, warnings, nil
+}
@@ -0,0 +1,90 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools"
+)
+
+func TestSolutionCommands(t *testing.T) {
+	app := project.Model{
+		Name:      "App",
+		Pth:       "/sln/App/App.csproj",
+		ConfigMap: map[string]string{"Release|iPhone": "Release|iPhone"},
+		Configs: map[string]project.ConfigurationPlatformModel{
+			"Release|iPhone": {Configuration: "Release", Platform: "iPhone"},
+		},
+	}
+	lib := project.Model{
+		Name:      "Lib",
+		Pth:       "/sln/Lib/Lib.csproj",
+		ConfigMap: map[string]string{"Release|iPhone": "Release|AnyCPU"},
+		Configs: map[string]project.ConfigurationPlatformModel{
+			"Release|AnyCPU": {Configuration: "Release", Platform: "AnyCPU"},
+		},
+	}
+	// not built in the Release|iPhone solution config
+	tests := project.Model{Name: "Tests", Pth: "/sln/Tests/Tests.csproj", ConfigMap: map[string]string{}}
+
+	filter := solution.Model{
+		Pth:             "/sln/Filters/Filter.slnf",
+		BaseSolutionPth: "/sln/Solution.sln",
+		ProjectMap:      map[string]project.Model{"APP": app, "LIB": lib, "TESTS": tests},
+	}
+
+	cases := []struct {
+		name      string
+		solution  solution.Model
+		buildTool buildtools.BuildTool
+		want      []string
+	}{
+		{
+			name:      "msbuild builds the solution filter",
+			solution:  filter,
+			buildTool: buildtools.Msbuild,
+			want: []string{
+				`"` + constants.MsbuildPath + `" "/sln/Filters/Filter.slnf" "/target:Clean" "/p:SolutionDir=/sln/" "/p:Configuration=Release" "/p:Platform=iPhone"`,
+			},
+		},
+		{
+			name:      "xbuild builds the filtered projects of the base solution",
+			solution:  filter,
+			buildTool: buildtools.Xbuild,
+			want: []string{
+				`"` + constants.XbuildPath + `" "/sln/App/App.csproj" "/target:Clean" "/p:SolutionDir=/sln/" "/p:Configuration=Release" "/p:Platform=iPhone"`,
+				`"` + constants.XbuildPath + `" "/sln/Lib/Lib.csproj" "/target:Clean" "/p:SolutionDir=/sln/" "/p:Configuration=Release" "/p:Platform=AnyCPU"`,
+			},
+		},
+		{
+			name:      "xbuild builds the solution",
+			solution:  solution.Model{Pth: "/sln/Solution.sln", ProjectMap: filter.ProjectMap},
+			buildTool: buildtools.Xbuild,
+			want: []string{
+				`"` + constants.XbuildPath + `" "/sln/Solution.sln" "/target:Clean" "/p:SolutionDir=/sln/" "/p:Configuration=Release" "/p:Platform=iPhone"`,
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := Model{solution: tt.solution, buildTool: tt.buildTool}
+
+			commands, err := builder.solutionCommands("Clean", "Release", "iPhone")
+			if err != nil {
+				t.Fatalf("solutionCommands() error = %s", err)
+			}
+
+			commandStrs := []string{}
+			for _, command := range commands {
+				commandStrs = append(commandStrs, command.String())
+			}
+			if !reflect.DeepEqual(commandStrs, tt.want) {
+				t.Errorf("solutionCommands() = %v, want %v", commandStrs, tt.want)
+			}
+		})
+	}
+}
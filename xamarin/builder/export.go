@@ -7,8 +7,8 @@ import (
 	"regexp"
 	"time"
 
-	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
 )
 
 // ModTimesByPath ...
@@ -23,7 +23,7 @@ func findModTimesByPath(dir string, excludeDir bool) (ModTimesByPath, error) {
 		if err != nil {
 			return err
 		}
-		log.Debugf("Walking for path: %s, modtime %v", path, info.ModTime())
+		logging.Debugf("Walking for path: %s, modtime %v", path, info.ModTime())
 
 		if excludeDir && info.IsDir() {
 			return nil
@@ -47,7 +47,7 @@ func isInTimeInterval(t, startTime, endTime time.Time) bool {
 }
 
 func filterModTimesByPathByTimeWindow(modTimesByPath ModTimesByPath, startTime, endTime time.Time) ModTimesByPath {
-	log.Debugf("Start time: %v, End time: %v", startTime, endTime)
+	logging.Debugf("Start time: %v, End time: %v", startTime, endTime)
 	if startTime.IsZero() || endTime.IsZero() || startTime.Equal(endTime) || startTime.After(endTime) {
 		return ModTimesByPath{}
 	}
@@ -57,9 +57,9 @@ func filterModTimesByPathByTimeWindow(modTimesByPath ModTimesByPath, startTime,
 	for pth, modTime := range modTimesByPath {
 		if isInTimeInterval(modTime, startTime, endTime) {
 			filteredModTimesByPath[pth] = modTime
-			log.Debugf("%s is in interval", pth)
+			logging.Debugf("%s is in interval", pth)
 		} else {
-			log.Debugf("%s is not in interval, filtering out", pth)
+			logging.Debugf("%s is not in interval, filtering out", pth)
 		}
 	}
 
@@ -70,7 +70,7 @@ func filterModTimesByPathByTimeWindow(modTimesByPath ModTimesByPath, startTime,
 // order of regexps should be: most strict -> less strict
 func findLastModifiedPathWithFileNameRegexps(modTimesByPath ModTimesByPath, regexps ...*regexp.Regexp) string {
 	if len(modTimesByPath) == 0 {
-		log.Debugf("Mod times by path is empty")
+		logging.Debugf("Mod times by path is empty")
 		return ""
 	}
 
@@ -79,9 +79,9 @@ func findLastModifiedPathWithFileNameRegexps(modTimesByPath ModTimesByPath, rege
 
 	if len(regexps) > 0 {
 		for _, re := range regexps {
-			log.Debugf("Checking match for regexp: %s", re)
+			logging.Debugf("Checking match for regexp: %s", re)
 			for pth, modTime := range modTimesByPath {
-				log.Debugf("Checking file at: %s mod time %s", pth, modTime)
+				logging.Debugf("Checking file at: %s mod time %s", pth, modTime)
 				fileName := filepath.Base(pth)
 				if re.MatchString(fileName) {
 					if modTime.After(lastModTime) {
@@ -97,9 +97,9 @@ func findLastModifiedPathWithFileNameRegexps(modTimesByPath ModTimesByPath, rege
 			}
 		}
 	} else {
-		log.Debugf("Regexps is empty")
+		logging.Debugf("Regexps is empty")
 		for pth, modTime := range modTimesByPath {
-			log.Debugf("Checking mod time: %s. Last mod time: %s", modTime, lastModTime)
+			logging.Debugf("Checking mod time: %s. Last mod time: %s", modTime, lastModTime)
 			if modTime.After(lastModTime) {
 				lastModifiedPth = pth
 				lastModTime = modTime
@@ -115,7 +115,7 @@ func findLastModifiedPathWithFileNameRegexps(modTimesByPath ModTimesByPath, rege
 // for directories as well or not. Please note, that for example a .xcarchive file qualifies as a directory, so if you
 // want to find it, the boolean should be false.
 func findArtifact(dir string, startTime, endTime time.Time, excludeDirs bool, patterns ...string) (string, error) {
-	log.Debugf("Searching at %s", dir)
+	logging.Debugf("Searching at %s", dir)
 	regexps := make([]*regexp.Regexp, len(patterns))
 	for i, pattern := range patterns {
 		regexps[i] = regexp.MustCompile(pattern)
@@ -139,6 +139,29 @@ func exportApk(outputDir, assemblyName string, startTime, endTime time.Time) (st
 	)
 }
 
+// exportPerABIApks returns the APK of each ABI, built with AndroidCreatePackagePerAbi, like <package>-arm64-v8a-Signed.apk.
+func exportPerABIApks(outputDir, packageName string, abis []string, startTime, endTime time.Time) (map[string]string, error) {
+	apkByABI := map[string]string{}
+
+	for _, abi := range abis {
+		quotedABI := regexp.QuoteMeta(abi)
+
+		apkPth, err := findArtifact(outputDir, startTime, endTime, false,
+			fmt.Sprintf(`(?i)^%s.*-%s-signed\.apk$`, regexp.QuoteMeta(packageName), quotedABI),
+			fmt.Sprintf(`(?i)-%s-signed\.apk$`, quotedABI),
+			fmt.Sprintf(`(?i)-%s\.apk$`, quotedABI),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if apkPth != "" {
+			apkByABI[abi] = apkPth
+		}
+	}
+
+	return apkByABI, nil
+}
+
 func exportAab(outputDir, assemblyName string, startTime, endTime time.Time) (string, error) {
 	return findArtifact(outputDir, startTime, endTime, false,
 		fmt.Sprintf(`(?i).*%s.*signed.*\.aab$`, assemblyName),
@@ -162,19 +185,25 @@ func exportXCArchive(outputDir, assemblyName string, startTime, endTime time.Tim
 	)
 }
 
-func exportLatestXCArchiveFromXcodeArchives(assemblyName string, startTime, endTime time.Time) (string, error) {
+func xcodeArchivesDir() (string, error) {
 	userHomeDir, ok := os.LookupEnv("HOME")
 	if !ok {
 		return "", fmt.Errorf("failed to get user home dir")
 	}
-	xcodeArchivesDir := filepath.Join(userHomeDir, "Library/Developer/Xcode/Archives")
-	if exist, err := pathutil.IsDirExists(xcodeArchivesDir); err != nil {
+	return filepath.Join(userHomeDir, "Library/Developer/Xcode/Archives"), nil
+}
+
+// exportLatestXCArchive returns the project's xcarchive created in the archives dir during the build,
+// a missing archives dir has none.
+func exportLatestXCArchive(archivesDir, assemblyName string, startTime, endTime time.Time) (string, error) {
+	if exist, err := pathutil.IsDirExists(archivesDir); err != nil {
 		return "", err
 	} else if !exist {
-		return "", fmt.Errorf("no default Xcode archive path found at: %s", xcodeArchivesDir)
+		logging.Debugf("No archives dir found at: %s", archivesDir)
+		return "", nil
 	}
 
-	return exportXCArchive(xcodeArchivesDir, assemblyName, startTime, endTime)
+	return exportXCArchive(archivesDir, assemblyName, startTime, endTime)
 }
 
 func exportAppDSYM(outputDir, assemblyName string, startTime, endTime time.Time) (string, error) {
@@ -210,3 +239,93 @@ func exportDLL(outputDir, assemblyName string, startTime, endTime time.Time) (st
 		`(?i).*\.dll$`,
 	)
 }
+
+// androidABIs are the ABI directory names of the Android native libraries.
+var androidABIs = []string{"armeabi-v7a", "arm64-v8a", "x86", "x86_64"}
+
+func isAndroidABI(name string) bool {
+	for _, abi := range androidABIs {
+		if abi == name {
+			return true
+		}
+	}
+	return false
+}
+
+// androidBuildDirs returns the existing obj and bin directories of the Android project.
+func androidBuildDirs(projectDir string) ([]string, error) {
+	dirs := []string{}
+	for _, name := range []string{"obj", "bin"} {
+		dir := filepath.Join(projectDir, name)
+		if exist, err := pathutil.IsDirExists(dir); err != nil {
+			return nil, err
+		} else if exist {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// exportMapping returns the R8/ProGuard mapping.txt generated by the build, like obj/Release/proguard/mapping.txt.
+func exportMapping(projectDir string, startTime, endTime time.Time) (string, error) {
+	dirs, err := androidBuildDirs(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		if mappingPth, err := findArtifact(dir, startTime, endTime, true, `^mapping\.txt$`); err != nil {
+			return "", err
+		} else if mappingPth != "" {
+			return mappingPth, nil
+		}
+	}
+	return "", nil
+}
+
+// exportNativeSymbols returns the native-debug-symbols.zip generated by the build,
+// or the lib directory of the native libraries (lib/<abi>/*.so) built in the project's obj directory.
+func exportNativeSymbols(projectDir string, startTime, endTime time.Time) (string, error) {
+	dirs, err := androidBuildDirs(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		if zipPth, err := findArtifact(dir, startTime, endTime, true, `(?i)^native-debug-symbols\.zip$`); err != nil {
+			return "", err
+		} else if zipPth != "" {
+			return zipPth, nil
+		}
+	}
+
+	objDir := filepath.Join(projectDir, "obj")
+	if exist, err := pathutil.IsDirExists(objDir); err != nil || !exist {
+		return "", err
+	}
+
+	libModTimes := ModTimesByPath{}
+	if err := filepath.Walk(objDir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(pth) != ".so" || !isInTimeInterval(info.ModTime(), startTime, endTime) {
+			return nil
+		}
+
+		abiDir := filepath.Dir(pth)
+		libDir := filepath.Dir(abiDir)
+		if filepath.Base(libDir) != "lib" || !isAndroidABI(filepath.Base(abiDir)) {
+			return nil
+		}
+
+		if info.ModTime().After(libModTimes[libDir]) {
+			libModTimes[libDir] = info.ModTime()
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	return findLastModifiedPathWithFileNameRegexps(libModTimes), nil
+}
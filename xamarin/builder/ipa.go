@@ -0,0 +1,126 @@
+package builder
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/logging"
+)
+
+// exportIpaFromXCArchive packages the app of the xcarchive into an IPA, for the device builds which did not generate one.
+// The IPA contains the app in its Payload directory and the Swift libraries of the archive's SwiftSupport directory.
+func exportIpaFromXCArchive(xcarchivePth, outputDir, assemblyName string) (string, error) {
+	appPths, err := filepath.Glob(filepath.Join(xcarchivePth, "Products", "Applications", "*.app"))
+	if err != nil {
+		return "", err
+	}
+	if len(appPths) == 0 {
+		return "", fmt.Errorf("no app found in xcarchive (%s)", xcarchivePth)
+	}
+	appPth := appPths[0]
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+
+	ipaName := assemblyName
+	if ipaName == "" {
+		ipaName = strings.TrimSuffix(filepath.Base(appPth), ".app")
+	}
+	ipaPth := filepath.Join(outputDir, ipaName+".ipa")
+
+	ipaFile, err := os.Create(ipaPth)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ipa (%s), error: %s", ipaPth, err)
+	}
+
+	writer := zip.NewWriter(ipaFile)
+
+	zipErr := addDirToZip(writer, appPth, filepath.Join("Payload", filepath.Base(appPth)))
+	if zipErr == nil {
+		swiftSupportPth := filepath.Join(xcarchivePth, "SwiftSupport")
+		if info, err := os.Stat(swiftSupportPth); err == nil && info.IsDir() {
+			zipErr = addDirToZip(writer, swiftSupportPth, "SwiftSupport")
+		}
+	}
+
+	if err := writer.Close(); err != nil && zipErr == nil {
+		zipErr = err
+	}
+	if err := ipaFile.Close(); err != nil && zipErr == nil {
+		zipErr = err
+	}
+	if zipErr != nil {
+		if err := os.Remove(ipaPth); err != nil {
+			logging.Warnf("Failed to remove incomplete ipa (%s), error: %s", ipaPth, err)
+		}
+		return "", fmt.Errorf("failed to package ipa (%s), error: %s", ipaPth, zipErr)
+	}
+
+	return ipaPth, nil
+}
+
+// addDirToZip adds the content of the directory under the given name, keeping the file modes and the symlinks.
+func addDirToZip(writer *zip.Writer, dir, name string) error {
+	return filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPth, err := filepath.Rel(dir, pth)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(name, relPth))
+
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := writer.CreateHeader(header)
+			return err
+		}
+
+		header.Method = zip.Deflate
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(pth)
+			if err != nil {
+				return err
+			}
+
+			header.Method = zip.Store
+			entry, err := writer.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = entry.Write([]byte(target))
+			return err
+		}
+
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(pth)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				logging.Warnf("Failed to close (%s), error: %s", pth, err)
+			}
+		}()
+
+		_, err = io.Copy(entry, file)
+		return err
+	})
+}
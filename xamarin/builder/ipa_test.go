@@ -0,0 +1,181 @@
+package builder
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExportIpaFromXCArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	xcarchivePth := filepath.Join(dir, "App.xcarchive")
+	appPth := filepath.Join(xcarchivePth, "Products", "Applications", "App.app")
+	frameworkPth := filepath.Join(appPth, "Frameworks", "Lib.framework")
+
+	files := map[string]os.FileMode{
+		filepath.Join(appPth, "App"):                                    0755,
+		filepath.Join(appPth, "Info.plist"):                             0644,
+		filepath.Join(frameworkPth, "Versions", "A", "Lib"):             0755,
+		filepath.Join(xcarchivePth, "SwiftSupport", "iphoneos", "a.so"): 0644,
+		filepath.Join(xcarchivePth, "Info.plist"):                       0644,
+	}
+	for pth, mode := range files {
+		if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(pth, []byte(filepath.Base(pth)), mode); err != nil {
+			t.Fatal(err)
+		}
+		// the umask may clear the permission bits
+		if err := os.Chmod(pth, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join("Versions", "A", "Lib"), filepath.Join(frameworkPth, "Lib")); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(dir, "output")
+	ipaPth, err := exportIpaFromXCArchive(xcarchivePth, outputDir, "")
+	if err != nil {
+		t.Fatalf("exportIpaFromXCArchive() error = %s", err)
+	}
+	if want := filepath.Join(outputDir, "App.ipa"); ipaPth != want {
+		t.Errorf("exportIpaFromXCArchive() = %s, want %s", ipaPth, want)
+	}
+
+	reader, err := zip.OpenReader(ipaPth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	names := []string{}
+	entries := map[string]*zip.File{}
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+		entries[file.Name] = file
+	}
+	sort.Strings(names)
+
+	wantNames := []string{
+		"Payload/App.app/",
+		"Payload/App.app/App",
+		"Payload/App.app/Frameworks/",
+		"Payload/App.app/Frameworks/Lib.framework/",
+		"Payload/App.app/Frameworks/Lib.framework/Lib",
+		"Payload/App.app/Frameworks/Lib.framework/Versions/",
+		"Payload/App.app/Frameworks/Lib.framework/Versions/A/",
+		"Payload/App.app/Frameworks/Lib.framework/Versions/A/Lib",
+		"Payload/App.app/Info.plist",
+		"SwiftSupport/",
+		"SwiftSupport/iphoneos/",
+		"SwiftSupport/iphoneos/a.so",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("ipa entries = %v, want %v", names, wantNames)
+	}
+
+	symlink := entries["Payload/App.app/Frameworks/Lib.framework/Lib"]
+	if symlink.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink entry mode = %s, want a symlink", symlink.Mode())
+	}
+	if target := readZipEntry(t, symlink); target != "Versions/A/Lib" {
+		t.Errorf("symlink entry target = %s, want Versions/A/Lib", target)
+	}
+
+	executable := entries["Payload/App.app/App"]
+	if executable.Mode().Perm() != 0755 {
+		t.Errorf("executable entry mode = %s, want -rwxr-xr-x", executable.Mode())
+	}
+	if content := readZipEntry(t, executable); content != "App" {
+		t.Errorf("executable entry content = %s, want App", content)
+	}
+}
+
+func TestExportIpaFromXCArchiveNamedByAssembly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	xcarchivePth := filepath.Join(dir, "App.xcarchive")
+	appPth := filepath.Join(xcarchivePth, "Products", "Applications", "App.app")
+	if err := os.MkdirAll(appPth, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ipaPth, err := exportIpaFromXCArchive(xcarchivePth, dir, "MyApp")
+	if err != nil {
+		t.Fatalf("exportIpaFromXCArchive() error = %s", err)
+	}
+	if want := filepath.Join(dir, "MyApp.ipa"); ipaPth != want {
+		t.Errorf("exportIpaFromXCArchive() = %s, want %s", ipaPth, want)
+	}
+}
+
+func TestExportIpaFromXCArchiveWithoutApp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	xcarchivePth := filepath.Join(dir, "App.xcarchive")
+	if err := os.MkdirAll(filepath.Join(xcarchivePth, "Products", "Applications"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(dir, "output")
+	if _, err := exportIpaFromXCArchive(xcarchivePth, outputDir, "App"); err == nil || !strings.Contains(err.Error(), "no app found") {
+		t.Errorf("exportIpaFromXCArchive() error = %v, want no app found", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "App.ipa")); !os.IsNotExist(err) {
+		t.Errorf("ipa is created without an app, stat error = %v", err)
+	}
+}
+
+func readZipEntry(t *testing.T, file *zip.File) string {
+	reader, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
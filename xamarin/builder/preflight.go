@@ -0,0 +1,112 @@
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+// PreflightCheck validates the solution config and the project configurations of the buildable projects before the build.
+// It fails if a buildable project has no project configuration mapped to the solution config,
+// and warns about the projects which may not generate the expected outputs.
+func (builder Model) PreflightCheck(configuration, platform string) ([]string, error) {
+	warnings := []string{}
+
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return warnings, err
+	}
+
+	buildableProjects, warns := builder.buildableProjects(configuration, platform)
+	warnings = append(warnings, warns...)
+	if len(buildableProjects) == 0 {
+		return warnings, fmt.Errorf("No project to build found")
+	}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, proj := range buildableProjects {
+		projectConfigKey, _ := proj.MappedConfig(solutionConfig)
+
+		projectConfig, ok := proj.Config(projectConfigKey)
+		if !ok {
+			available := []string{}
+			for config := range proj.Configs {
+				available = append(available, config)
+			}
+			sort.Strings(available)
+
+			return warnings, fmt.Errorf("project (%s) maps solution config (%s) to project config (%s), but the project does not define it, available: %v", proj.Name, solutionConfig, projectConfigKey, available)
+		}
+
+		// OutputPath may come from imported targets or the SDK defaults, which are not analyzed
+		if projectConfig.OutputDir == "" {
+			warnings = append(warnings, fmt.Sprintf("Project (%s) config (%s) does not define OutputPath, its outputs may not be found", proj.Name, projectConfigKey))
+		}
+
+		if (proj.SDK == constants.SDKIOS || proj.SDK == constants.SDKTvOS) && !IsDeviceArch(projectConfig.MtouchArchs...) {
+			warnings = append(warnings, fmt.Sprintf("Project (%s) config (%s) builds for simulator only (MtouchArch: %s), no ipa will be generated", proj.Name, projectConfigKey, strings.Join(projectConfig.MtouchArchs, ", ")))
+		}
+	}
+
+	return warnings, nil
+}
+
+// closestConfig returns the available config most similar to the given one, or empty string if none is similar enough.
+// A case-insensitive match is preferred over the edit distance.
+func closestConfig(config string, available []string) string {
+	closest := ""
+	closestDistance := len(config)/2 + 1
+
+	for _, availableConfig := range available {
+		if strings.EqualFold(config, availableConfig) {
+			return availableConfig
+		}
+
+		if distance := editDistance(strings.ToLower(config), strings.ToLower(availableConfig)); distance < closestDistance {
+			closest = availableConfig
+			closestDistance = distance
+		}
+	}
+
+	return closest
+}
+
+// editDistance returns the Levenshtein distance of the given strings.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(br)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, value := range values[1:] {
+		if value < m {
+			m = value
+		}
+	}
+	return m
+}
@@ -0,0 +1,369 @@
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+func (builder Model) whitelistedProjects() []project.Model {
+	projects := []project.Model{}
+
+	for _, proj := range builder.solution.ProjectMap {
+		if !whitelistAllows(proj.SDK, builder.projectTypeWhitelist...) {
+			continue
+		}
+
+		if proj.SDK != constants.SDKUnknown {
+			projects = append(projects, proj)
+		}
+	}
+
+	return projects
+}
+
+// solutionProjects returns every project of the solution ordered by id.
+func (builder Model) solutionProjects() []project.Model {
+	ids := make([]string, 0, len(builder.solution.ProjectMap))
+	for id := range builder.solution.ProjectMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	projects := make([]project.Model, 0, len(ids))
+	for _, id := range ids {
+		projects = append(projects, builder.solution.ProjectMap[id])
+	}
+
+	return projects
+}
+
+// SkippedProject is a project of the solution, which is not archived.
+type SkippedProject struct {
+	Project project.Model
+	Reason  string
+	Warn    bool   // The reason or the project's analyzer diagnostics are worth a build warning
+	HostApp string // Name of the app the project is built into, for the app extension and watch projects
+}
+
+// SkippedProjects returns the projects of the solution not archived with the given solution config, ordered by name.
+// The skipped projects' analyzer diagnostics explain the unknown project types and the missing properties.
+func (builder Model) SkippedProjects(configuration, platform string) ([]SkippedProject, error) {
+	configuration, platform, err := resolveSolutionConfig(builder.solution, configuration, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	skipped := []SkippedProject{}
+	for _, proj := range builder.solution.ProjectMap {
+		if reason, warn := builder.skipReason(proj, solutionConfig); reason != "" {
+			skippedProject := SkippedProject{
+				Project: proj,
+				Reason:  reason,
+				Warn:    warn || proj.HasWarnings(),
+			}
+			if host, ok := builder.hostApp(proj); ok {
+				skippedProject.HostApp = host.Name
+			}
+			skipped = append(skipped, skippedProject)
+		}
+	}
+
+	sort.Slice(skipped, func(i, j int) bool {
+		if skipped[i].Project.Name != skipped[j].Project.Name {
+			return skipped[i].Project.Name < skipped[j].Project.Name
+		}
+		return skipped[i].Project.Pth < skipped[j].Project.Pth
+	})
+
+	return skipped, nil
+}
+
+// skipReason returns why the project is not archived with the given solution config, or empty string if it is archived.
+// warn reports if the reason is worth a build warning, unknown and not whitelisted project types are skipped silently.
+func (builder Model) skipReason(proj project.Model, solutionConfig string) (reason string, warn bool) {
+	if proj.SDK == constants.SDKUnknown {
+		return "project type is unknown", false
+	}
+
+	if !whitelistAllows(proj.SDK, builder.projectTypeWhitelist...) {
+		return fmt.Sprintf("project type (%s) is not whitelisted", proj.SDK), false
+	}
+
+	// app extensions and watch apps are archived as part of their host app
+	if host, ok := builder.hostApp(proj); ok {
+		return fmt.Sprintf("is built as part of its host app (%s)", host.Name), false
+	}
+	if proj.Kind.IsEmbedded() {
+		return fmt.Sprintf("is an embedded project (%s), but no app of the solution embeds it", proj.Kind), true
+	}
+
+	if proj.Kind == constants.ProjectKindBinding {
+		return "is a binding library", false
+	}
+
+	//
+	// Solution config - project config mapping
+	if _, ok := proj.MappedConfig(solutionConfig); !ok {
+		return fmt.Sprintf("do not have config for solution config (%s)", solutionConfig), true
+	}
+
+	if (proj.SDK == constants.SDKIOS ||
+		proj.SDK == constants.SDKMacOS ||
+		proj.SDK == constants.SDKMacCatalyst ||
+		proj.SDK == constants.SDKTvOS) &&
+		proj.OutputType != "exe" {
+		return fmt.Sprintf("is not archivable based on output type (%s)", proj.OutputType), true
+	}
+
+	if proj.SDK == constants.SDKAndroid &&
+		!proj.AndroidApplication {
+		return "is not an android application project", true
+	}
+
+	return "", false
+}
+
+// hostApp returns the top-level app the project is built into, following the embedding chain,
+// like watch extension - watch app - iOS app.
+func (builder Model) hostApp(proj project.Model) (project.Model, bool) {
+	host, found := project.Model{}, false
+	visited := map[string]bool{proj.Pth: true}
+
+	for {
+		embedder, ok := builder.embedderByPth[proj.Pth]
+		if !ok || visited[embedder.Pth] {
+			break
+		}
+		visited[embedder.Pth] = true
+
+		host, found = embedder, true
+		proj = embedder
+	}
+
+	return host, found
+}
+
+// embedders returns the embedding project of the solution's embedded projects by the embedded project path.
+func embedders(solution solution.Model) map[string]project.Model {
+	embedderByPth := map[string]project.Model{}
+	for _, candidate := range solution.ProjectMap {
+		for _, embeddedPth := range candidate.EmbeddedProjectPths {
+			embedderByPth[embeddedPth] = candidate
+		}
+	}
+	return embedderByPth
+}
+
+func (builder Model) buildableProjects(configuration, platform string) ([]project.Model, []string) {
+	projects := []project.Model{}
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, proj := range builder.solution.ProjectMap {
+		if reason, warn := builder.skipReason(proj, solutionConfig); reason != "" {
+			if warn {
+				warnings = append(warnings, fmt.Sprintf("Project (%s) %s, skipping...", proj.Name, reason))
+			}
+			continue
+		}
+
+		projects = append(projects, proj)
+	}
+
+	projects, warns := builder.sortProjectsByDependencies(projects)
+	warnings = append(warnings, warns...)
+
+	return projects, warnings
+}
+
+func (builder Model) buildableXamarinUITestProjectsAndReferredProjects(configuration, platform string) ([]project.Model, []project.Model, []string) {
+	testProjects := []project.Model{}
+	referredProjects := []project.Model{}
+
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, proj := range builder.solution.ProjectMap {
+		// Check if is XamarinUITest project
+		if proj.TestFramework != constants.TestFrameworkXamarinUITest {
+			continue
+		}
+
+		// Check if contains config mapping
+		_, ok := proj.MappedConfig(solutionConfig)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("Project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+			continue
+		}
+
+		// Collect referred projects
+		if len(proj.ReferredProjectIDs) == 0 {
+			warnings = append(warnings, fmt.Sprintf("No referred projects found for test project: %s, skipping...", proj.Name))
+			continue
+		}
+
+		for _, projectID := range proj.ReferredProjectIDs {
+			referredProj, ok := builder.solution.ProjectMap[projectID]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("Project reference exist with project id: %s, but project not found in solution", projectID))
+				continue
+			}
+
+			if referredProj.SDK == constants.SDKUnknown {
+				warnings = append(warnings, fmt.Sprintf("Project's (%s) project type is unkown", referredProj.Name))
+				continue
+			}
+
+			if whitelistAllows(referredProj.SDK, builder.projectTypeWhitelist...) {
+				referredProjects = append(referredProjects, referredProj)
+			}
+		}
+
+		if len(referredProjects) == 0 {
+			warnings = append(warnings, fmt.Sprintf("Test project (%s) does not refers to any project, with project type whitelist (%v), skipping...", proj.Name, builder.projectTypeWhitelist))
+			continue
+		}
+
+		testProjects = append(testProjects, proj)
+	}
+
+	return testProjects, referredProjects, warnings
+}
+
+func (builder Model) buildableNunitTestProjects(configuration, platform string) ([]project.Model, []string) {
+	testProjects := []project.Model{}
+
+	warnings := []string{}
+
+	solutionConfig := utility.ToConfig(configuration, platform)
+
+	for _, proj := range builder.solution.ProjectMap {
+		// Check if is nunit test project
+		if proj.TestFramework != constants.TestFrameworkNunitTest {
+			continue
+		}
+
+		// Check if contains config mapping
+		_, ok := proj.MappedConfig(solutionConfig)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("Project (%s) do not have config for solution config (%s), skipping...", proj.Name, solutionConfig))
+			continue
+		}
+
+		testProjects = append(testProjects, proj)
+	}
+
+	testProjects, warns := builder.sortProjectsByDependencies(testProjects)
+	warnings = append(warnings, warns...)
+
+	return testProjects, warnings
+}
+
+// sortProjectsByDependencies orders the projects so that each project follows the projects it depends on,
+// by the solution level build dependencies and the project references. The order is computed on every project
+// of the solution, so a dependency through a project which is not in the list (for example a library) is kept too.
+// Independent projects are ordered by name, the projects of a dependency cycle are appended by name, with a warning.
+func (builder Model) sortProjectsByDependencies(projects []project.Model) ([]project.Model, []string) {
+	warnings := []string{}
+
+	// the solution refers to a project by its solution id, the project references by the ProjectGuid
+	pthByID := map[string]string{}
+	projectByPth := map[string]project.Model{}
+	for projectID, proj := range builder.solution.ProjectMap {
+		pthByID[projectID] = proj.Pth
+		if proj.ID != "" {
+			pthByID[proj.ID] = proj.Pth
+		}
+		projectByPth[proj.Pth] = proj
+	}
+
+	dependenciesByPth := map[string]map[string]bool{}
+	for projectID, proj := range builder.solution.ProjectMap {
+		dependencies := dependenciesByPth[proj.Pth]
+		if dependencies == nil {
+			dependencies = map[string]bool{}
+			dependenciesByPth[proj.Pth] = dependencies
+		}
+
+		dependencyIDs := append(append([]string{}, builder.solution.ProjectDependencies[projectID]...), proj.ReferredProjectIDs...)
+		for _, dependencyID := range dependencyIDs {
+			if dependencyPth, ok := pthByID[dependencyID]; ok && dependencyPth != proj.Pth {
+				dependencies[dependencyPth] = true
+			}
+		}
+	}
+
+	remaining := []project.Model{}
+	for _, proj := range projectByPth {
+		remaining = append(remaining, proj)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		if remaining[i].Name != remaining[j].Name {
+			return remaining[i].Name < remaining[j].Name
+		}
+		return remaining[i].Pth < remaining[j].Pth
+	})
+
+	selected := map[string]bool{}
+	for _, proj := range projects {
+		selected[proj.Pth] = true
+	}
+
+	sorted := []project.Model{}
+	done := map[string]bool{}
+
+	for len(remaining) > 0 {
+		next := -1
+		for i, proj := range remaining {
+			ready := true
+			for dependencyPth := range dependenciesByPth[proj.Pth] {
+				if !done[dependencyPth] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			names := []string{}
+			for _, proj := range remaining {
+				if selected[proj.Pth] {
+					names = append(names, proj.Name)
+				}
+			}
+			if len(names) > 0 {
+				warnings = append(warnings, fmt.Sprintf("Projects (%s) depend on each other, building them in alphabetical order", strings.Join(names, ", ")))
+			}
+
+			sorted = append(sorted, remaining...)
+			break
+		}
+
+		done[remaining[next].Pth] = true
+		sorted = append(sorted, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+
+	ordered := []project.Model{}
+	for _, proj := range sorted {
+		if selected[proj.Pth] {
+			ordered = append(ordered, proj)
+		}
+	}
+
+	return ordered, warnings
+}
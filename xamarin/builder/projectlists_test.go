@@ -0,0 +1,142 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+)
+
+func TestSortProjectsByDependencies(t *testing.T) {
+	app := project.Model{Name: "App", Pth: "/sln/App/App.csproj", ID: "APP-GUID", ReferredProjectIDs: []string{"LIB-GUID"}}
+	lib := project.Model{Name: "Lib", Pth: "/sln/Lib/Lib.csproj", ID: "LIB-GUID"}
+	otherApp := project.Model{Name: "OtherApp", Pth: "/sln/OtherApp/OtherApp.csproj", ID: "OTHERAPP-GUID"}
+	independent := project.Model{Name: "Independent", Pth: "/sln/Independent/Independent.csproj", ID: "INDEPENDENT-GUID"}
+
+	tests := []struct {
+		name                string
+		projectMap          map[string]project.Model
+		projectDependencies map[string][]string
+		projects            []project.Model
+		want                []string
+		wantWarnings        int
+	}{
+		{
+			name: "independent projects are ordered by name",
+			projectMap: map[string]project.Model{
+				"SLN-OTHERAPP":    otherApp,
+				"SLN-INDEPENDENT": independent,
+			},
+			projects: []project.Model{otherApp, independent},
+			want:     []string{"Independent", "OtherApp"},
+		},
+		{
+			name: "project reference through a library which is not built",
+			projectMap: map[string]project.Model{
+				"SLN-APP":      app,
+				"SLN-LIB":      lib,
+				"SLN-OTHERAPP": otherApp,
+			},
+			projectDependencies: map[string][]string{
+				"SLN-LIB": {"SLN-OTHERAPP"},
+			},
+			projects: []project.Model{app, otherApp},
+			want:     []string{"OtherApp", "App"},
+		},
+		{
+			name: "solution dependency through a library which is not built",
+			projectMap: map[string]project.Model{
+				"SLN-APP":      {Name: "App", Pth: app.Pth, ID: app.ID},
+				"SLN-LIB":      {Name: "Lib", Pth: lib.Pth, ID: lib.ID, ReferredProjectIDs: []string{"OTHERAPP-GUID"}},
+				"SLN-OTHERAPP": otherApp,
+			},
+			projectDependencies: map[string][]string{
+				"SLN-APP": {"SLN-LIB"},
+			},
+			projects: []project.Model{{Name: "App", Pth: app.Pth, ID: app.ID}, otherApp},
+			want:     []string{"OtherApp", "App"},
+		},
+		{
+			name: "dependency cycle",
+			projectMap: map[string]project.Model{
+				"SLN-APP":      app,
+				"SLN-LIB":      lib,
+				"SLN-OTHERAPP": {Name: "OtherApp", Pth: otherApp.Pth, ID: otherApp.ID, ReferredProjectIDs: []string{"APP-GUID"}},
+			},
+			projectDependencies: map[string][]string{
+				"SLN-LIB": {"SLN-OTHERAPP"},
+			},
+			projects:     []project.Model{app, otherApp},
+			want:         []string{"App", "OtherApp"},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectDependencies := tt.projectDependencies
+			if projectDependencies == nil {
+				projectDependencies = map[string][]string{}
+			}
+			builder := Model{
+				solution: solution.Model{
+					ProjectMap:          tt.projectMap,
+					ProjectDependencies: projectDependencies,
+				},
+			}
+
+			sorted, warnings := builder.sortProjectsByDependencies(tt.projects)
+
+			names := []string{}
+			for _, proj := range sorted {
+				names = append(names, proj.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("sortProjectsByDependencies() = %v, want %v", names, tt.want)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("sortProjectsByDependencies() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSkippedProjectsHostApp(t *testing.T) {
+	config := map[string]string{"Release|iPhone": "Release|iPhone"}
+	app := project.Model{Name: "App", Pth: "/sln/App/App.csproj", SDK: constants.SDKIOS, Kind: constants.ProjectKindApp, OutputType: "exe", ConfigMap: config, EmbeddedProjectPths: []string{"/sln/WatchApp/WatchApp.csproj"}}
+	watchApp := project.Model{Name: "WatchApp", Pth: "/sln/WatchApp/WatchApp.csproj", SDK: constants.SDKIOS, Kind: constants.ProjectKindWatchApp, ConfigMap: config, EmbeddedProjectPths: []string{"/sln/WatchExtension/WatchExtension.csproj"}}
+	watchExtension := project.Model{Name: "WatchExtension", Pth: "/sln/WatchExtension/WatchExtension.csproj", SDK: constants.SDKIOS, Kind: constants.ProjectKindWatchExtension, ConfigMap: config}
+	orphanExtension := project.Model{Name: "OrphanExtension", Pth: "/sln/OrphanExtension/OrphanExtension.csproj", SDK: constants.SDKTvOS, Kind: constants.ProjectKindAppExtension, ConfigMap: config}
+
+	sln := solution.Model{
+		ConfigMap: map[string]string{"Release|iPhone": "Release|iPhone"},
+		ProjectMap: map[string]project.Model{
+			"APP":             app,
+			"WATCHAPP":        watchApp,
+			"WATCHEXTENSION":  watchExtension,
+			"ORPHANEXTENSION": orphanExtension,
+		},
+	}
+	builder := Model{solution: sln, embedderByPth: embedders(sln)}
+
+	skipped, err := builder.SkippedProjects("Release", "iPhone")
+	if err != nil {
+		t.Fatalf("SkippedProjects() error = %s", err)
+	}
+
+	got := map[string]string{}
+	warns := map[string]bool{}
+	for _, skippedProject := range skipped {
+		got[skippedProject.Project.Name] = skippedProject.HostApp
+		warns[skippedProject.Project.Name] = skippedProject.Warn
+	}
+	want := map[string]string{"WatchApp": "App", "WatchExtension": "App", "OrphanExtension": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SkippedProjects() host apps = %v, want %v", got, want)
+	}
+	if !warns["OrphanExtension"] || warns["WatchExtension"] {
+		t.Errorf("SkippedProjects() warns = %v, want the orphan extension only", warns)
+	}
+}
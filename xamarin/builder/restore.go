@@ -0,0 +1,305 @@
+package builder
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/project"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/buildtools/msbuild"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools/nuget"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
+)
+
+const (
+	packagesConfigFileName = "packages.config"
+	projectAssetsFileName  = "project.assets.json"
+	nugetConfigFileName    = "nuget.config"
+)
+
+// ProjectsMissingRestore returns the solution's projects whose NuGet packages are not restored:
+// PackageReference (and SDK-style) projects without obj/project.assets.json
+// and packages.config projects with packages missing from the packages dir.
+// Every project is checked, not only the buildable ones, as the referenced libraries are built too.
+// The packages dir is the repositoryPath of the given NuGet config file (or of the NuGet configs
+// of the solution dir and its parents, if empty), <solution dir>/packages by default.
+func (builder Model) ProjectsMissingRestore(configFile string) ([]project.Model, error) {
+	packagesDir, err := builder.packagesDir(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []project.Model{}
+	for _, proj := range builder.solutionProjects() {
+		restored, err := isRestored(proj, packagesDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if !restored {
+			projects = append(projects, proj)
+		}
+	}
+
+	return projects, nil
+}
+
+// RestoreNugetPackages ...
+func (builder Model) RestoreNugetPackages(projects []project.Model, source, configFile string, callback BuildCommandCallback) error {
+	return builder.RestoreNugetPackagesWithContext(context.Background(), projects, source, configFile, callback)
+}
+
+// RestoreNugetPackagesWithContext restores the solution's NuGet packages,
+// with nuget restore if any of the given projects uses packages.config or the build tool is xbuild
+// (which has no Restore target), otherwise with the Restore msbuild target.
+// nuget restores the projects of a solution filter one by one, as it does not support solution filters.
+func (builder Model) RestoreNugetPackagesWithContext(ctx context.Context, projects []project.Model, source, configFile string, callback BuildCommandCallback) error {
+	usesPackagesConfig := false
+	for _, proj := range projects {
+		if exist, err := pathutil.IsPathExists(packagesConfigPth(proj)); err != nil {
+			return err
+		} else if exist {
+			usesPackagesConfig = true
+			break
+		}
+	}
+
+	type restoreCommand struct {
+		command tools.Runnable
+		name    string
+	}
+	restoreCommands := []restoreCommand{}
+
+	if usesPackagesConfig || builder.buildTool != buildtools.Msbuild {
+		type restoreTarget struct {
+			pth  string
+			name string
+		}
+		targets := []restoreTarget{{pth: builder.solution.Pth, name: builder.solution.Name}}
+
+		// nuget restore does not support solution filters, the filtered projects of the base solution are restored one by one
+		if builder.solution.BaseSolutionPth != "" {
+			targets = []restoreTarget{}
+			for _, proj := range builder.solutionProjects() {
+				targets = append(targets, restoreTarget{pth: proj.Pth, name: proj.Name})
+			}
+		}
+
+		for _, target := range targets {
+			command, err := nuget.New(target.pth)
+			if err != nil {
+				return err
+			}
+
+			if builder.solution.BaseSolutionPth != "" {
+				command.SetSolutionDirectory(filepath.Dir(builder.solution.BaseSolutionPth))
+			}
+			command.SetSource(source)
+			command.SetConfigFile(configFile)
+			restoreCommands = append(restoreCommands, restoreCommand{command: command, name: target.name})
+		}
+	} else {
+		command, err := msbuild.New(builder.solution.Pth, "")
+		if err != nil {
+			return err
+		}
+
+		command.SetTarget("Restore")
+
+		options := []string{}
+		if source != "" {
+			options = append(options, "/p:RestoreSources="+source)
+		}
+		if configFile != "" {
+			options = append(options, "/p:RestoreConfigFile="+configFile)
+		}
+		command.SetCustomOptions(options...)
+		restoreCommands = append(restoreCommands, restoreCommand{command: command, name: builder.solution.Name})
+	}
+
+	for _, restore := range restoreCommands {
+		// Callback to notify the caller about next running command
+		if callback != nil {
+			callback(builder.solution.Name, "", constants.SDKUnknown, constants.TestFrameworkUnknown, restore.command.String(), false)
+		}
+
+		if err := builder.runCommand(ctx, restore.command, restore.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func packagesConfigPth(proj project.Model) string {
+	return filepath.Join(filepath.Dir(proj.Pth), packagesConfigFileName)
+}
+
+func isRestored(proj project.Model, packagesDir string) (bool, error) {
+	pth := packagesConfigPth(proj)
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return false, err
+	} else if exist {
+		return isPackagesConfigRestored(pth, packagesDir)
+	}
+
+	if !proj.SDKStyle && len(proj.PackageReferences) == 0 {
+		return true, nil
+	}
+
+	assetsPth := filepath.Join(filepath.Dir(proj.Pth), "obj", projectAssetsFileName)
+	return pathutil.IsPathExists(assetsPth)
+}
+
+// packagesDir returns the directory the packages.config projects' packages are extracted into.
+func (builder Model) packagesDir(configFile string) (string, error) {
+	solutionDir := filepath.Dir(builder.solution.SolutionPth())
+
+	if configFile != "" {
+		repositoryPath, err := nugetRepositoryPath(configFile)
+		if err != nil {
+			return "", err
+		}
+		if repositoryPath != "" {
+			return repositoryPath, nil
+		}
+		return filepath.Join(solutionDir, "packages"), nil
+	}
+
+	// the closest config defining repositoryPath wins, like in the NuGet config hierarchy
+	dir := solutionDir
+	for {
+		configPth, err := findNugetConfig(dir)
+		if err != nil {
+			return "", err
+		}
+
+		if configPth != "" {
+			repositoryPath, err := nugetRepositoryPath(configPth)
+			if err != nil {
+				return "", err
+			}
+			if repositoryPath != "" {
+				return repositoryPath, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return filepath.Join(solutionDir, "packages"), nil
+}
+
+// findNugetConfig returns the NuGet config file of the directory, the file name is matched case-insensitively.
+func findNugetConfig(dir string) (string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() && strings.EqualFold(info.Name(), nugetConfigFileName) {
+			return filepath.Join(dir, info.Name()), nil
+		}
+	}
+
+	return "", nil
+}
+
+// nugetRepositoryPath returns the absolute repositoryPath of the NuGet config file, or empty string if it is not set.
+func nugetRepositoryPath(configPth string) (string, error) {
+	content, err := fileutil.ReadBytesFromFile(configPth)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s, error: %s", configPth, err)
+	}
+
+	var nugetConfig struct {
+		Config struct {
+			Adds []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"add"`
+		} `xml:"config"`
+	}
+	if err := xml.Unmarshal(content, &nugetConfig); err != nil {
+		return "", fmt.Errorf("failed to parse %s, error: %s", configPth, err)
+	}
+
+	for _, add := range nugetConfig.Config.Adds {
+		if !strings.EqualFold(add.Key, "repositoryPath") || add.Value == "" {
+			continue
+		}
+
+		repositoryPath := utility.FixWindowsPath(add.Value)
+		if !filepath.IsAbs(repositoryPath) {
+			repositoryPath = filepath.Join(filepath.Dir(configPth), repositoryPath)
+		}
+		return repositoryPath, nil
+	}
+
+	return "", nil
+}
+
+// isPackagesConfigRestored checks if every package listed in the packages.config
+// is extracted into the packages dir as <id>.<version>.
+func isPackagesConfigRestored(packagesConfigPth, packagesDir string) (bool, error) {
+	content, err := fileutil.ReadBytesFromFile(packagesConfigPth)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s, error: %s", packagesConfigPth, err)
+	}
+
+	var packagesConfig struct {
+		Packages []struct {
+			ID      string `xml:"id,attr"`
+			Version string `xml:"version,attr"`
+		} `xml:"package"`
+	}
+	if err := xml.Unmarshal(content, &packagesConfig); err != nil {
+		return false, fmt.Errorf("failed to parse %s, error: %s", packagesConfigPth, err)
+	}
+
+	if len(packagesConfig.Packages) == 0 {
+		return true, nil
+	}
+
+	if exist, err := pathutil.IsDirExists(packagesDir); err != nil {
+		return false, err
+	} else if !exist {
+		return false, nil
+	}
+
+	infos, err := ioutil.ReadDir(packagesDir)
+	if err != nil {
+		return false, err
+	}
+
+	extractedPackages := map[string]bool{}
+	for _, info := range infos {
+		if info.IsDir() {
+			extractedPackages[strings.ToLower(info.Name())] = true
+		}
+	}
+
+	for _, pkg := range packagesConfig.Packages {
+		if !extractedPackages[strings.ToLower(pkg.ID+"."+pkg.Version)] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -8,15 +8,17 @@ import (
 
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/go-xamarin/analyzers/solution"
-	"github.com/bitrise-io/go-xamarin/constants"
-	"github.com/bitrise-io/go-xamarin/utility"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/analyzers/solution"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/utility"
 )
 
 func validateSolutionPth(pth string) error {
 	ext := filepath.Ext(pth)
-	if ext != constants.SolutionExt {
-		return fmt.Errorf("path is not a solution file path: %s", pth)
+	switch ext {
+	case constants.SolutionExt, constants.SolutionXMLExt, constants.SolutionFilterExt, constants.CSProjExt, constants.FSProjExt:
+	default:
+		return fmt.Errorf("path is not a solution or project file path: %s", pth)
 	}
 	if exist, err := pathutil.IsPathExists(pth); err != nil {
 		return err
@@ -26,12 +28,18 @@ func validateSolutionPth(pth string) error {
 	return nil
 }
 
-func validateSolutionConfig(solution solution.Model, configuration, platform string) error {
-	config := utility.ToConfig(configuration, platform)
-	if _, ok := solution.ConfigMap[config]; !ok {
-		return fmt.Errorf("invalid solution config: %s, available: %v", config, solution.ConfigList())
+// resolveSolutionConfig returns the solution's Configuration and Platform matching the given ones.
+func resolveSolutionConfig(solution solution.Model, configuration, platform string) (string, string, error) {
+	resolvedConfiguration, resolvedPlatform, ok := solution.ResolveConfig(configuration, platform)
+	if !ok {
+		config := utility.ToConfig(configuration, platform)
+		available := solution.ConfigList()
+		if suggestion := closestConfig(config, available); suggestion != "" {
+			return "", "", fmt.Errorf("invalid solution config: %s, did you mean: %s? available: %v", config, suggestion, available)
+		}
+		return "", "", fmt.Errorf("invalid solution config: %s, available: %v", config, available)
 	}
-	return nil
+	return resolvedConfiguration, resolvedPlatform, nil
 }
 
 func whitelistAllows(projectType constants.SDK, projectTypeWhiteList ...constants.SDK) bool {
@@ -53,6 +61,10 @@ func whitelistAllows(projectType constants.SDK, projectTypeWhiteList ...constant
 			if projectType == constants.SDKMacOS {
 				return true
 			}
+		case constants.SDKMacCatalyst:
+			if projectType == constants.SDKMacCatalyst {
+				return true
+			}
 		case constants.SDKAndroid:
 			if projectType == constants.SDKAndroid {
 				return true
@@ -72,7 +84,7 @@ func IsDeviceArch(architectures ...string) bool {
 }
 
 func isPlatformAnyCPU(platform string) bool {
-	return (platform == "Any CPU" || platform == "AnyCPU")
+	return utility.NormalizeConfig(platform) == "anycpu"
 }
 
 func androidPackageName(manifestPth string) (string, error) {
@@ -103,3 +115,14 @@ func androidPackageNameFromManifestContent(manifestContent string) (string, erro
 
 	return result.Manifest.Package, nil
 }
+
+// macCatalystTargetFramework returns the Mac Catalyst target framework of the given target frameworks,
+// or empty string if none of them targets Mac Catalyst.
+func macCatalystTargetFramework(targetFrameworks []string) string {
+	for _, targetFramework := range targetFrameworks {
+		if sdk, err := constants.ParseTargetFramework(targetFramework); err == nil && sdk == constants.SDKMacCatalyst {
+			return targetFramework
+		}
+	}
+	return ""
+}
@@ -1,6 +1,9 @@
 package constants
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	// MsbuildPath ...
@@ -10,11 +13,18 @@ const (
 
 	// MonoPath ...
 	MonoPath = "/Library/Frameworks/Mono.framework/Versions/Current/Commands/mono"
+
+	// NugetPath ...
+	NugetPath = "/Library/Frameworks/Mono.framework/Versions/Current/Commands/nuget"
 )
 
 const (
 	// SolutionExt ...
 	SolutionExt = ".sln"
+	// SolutionXMLExt ...
+	SolutionXMLExt = ".slnx"
+	// SolutionFilterExt ...
+	SolutionFilterExt = ".slnf"
 	// CSProjExt ...
 	CSProjExt = ".csproj"
 	// FSProjExt ...
@@ -37,6 +47,8 @@ const (
 	SDKTvOS SDK = "tvos"
 	// SDKMacOS ...
 	SDKMacOS SDK = "macos"
+	// SDKMacCatalyst ...
+	SDKMacCatalyst SDK = "maccatalyst"
 )
 
 // ParseSDK ...
@@ -50,6 +62,8 @@ func ParseSDK(sdk string) (SDK, error) {
 		return SDKTvOS, nil
 	case "macos":
 		return SDKMacOS, nil
+	case "maccatalyst":
+		return SDKMacCatalyst, nil
 	default:
 		return SDKUnknown, fmt.Errorf("invalid sdk: %s", sdk)
 	}
@@ -83,28 +97,60 @@ func ParseTestFramwork(testFramwork string) (TestFramework, error) {
 	}
 }
 
-// ParseProjectTypeGUID ...
-func ParseProjectTypeGUID(guid string) (SDK, error) {
-	switch guid {
-	case "EFBA0AD7-5A72-4C68-AF49-83D382785DCF",
-		"10368E6C-D01B-4462-8E8B-01FC667A7035": // XamarinAndroid
+// ParseTargetFramework returns the SDK of a target framework moniker,
+// like net8.0-ios, net8.0-android34.0, net8.0-maccatalyst or the legacy MonoAndroid90, Xamarin.iOS10 and Xamarin.MacCatalyst.
+func ParseTargetFramework(tfm string) (SDK, error) {
+	framework := strings.ToLower(strings.TrimSpace(tfm))
+	if i := strings.Index(framework, "-"); i >= 0 {
+		// .NET TFM: the platform follows the dash, optionally followed by its version
+		framework = strings.TrimRight(framework[i+1:], "0123456789.")
+	}
+	framework = strings.Replace(framework, ".", "", -1)
+
+	switch {
+	case framework == "android" || strings.HasPrefix(framework, "monoandroid"):
 		return SDKAndroid, nil
-	case "E613F3A2-FE9C-494F-B74E-F63BCB86FEA6", // XamarinIOS
-		"6BC8ED88-2882-458C-8E55-DFD12B67127B",
-		"F5B4F3BC-B597-4E2B-B552-EF5D8A32436F",
-		"FEACFBD2-3405-455C-9665-78FE426C6842",
-		"8FFB629D-F513-41CE-95D2-7ECE97B6EEEC",
-		"EE2C853D-36AF-4FDB-B1AD-8E90477E2198":
+	case framework == "ios" || strings.HasPrefix(framework, "xamarinios"):
 		return SDKIOS, nil
-	case "06FA79CB-D6CD-4721-BB4B-1BD202089C55": // XamarinProjectTypeTvOS
+	case framework == "tvos" || strings.HasPrefix(framework, "xamarintvos"):
 		return SDKTvOS, nil
-	case "1C533B1C-72DD-4CB1-9F6B-BF11D93BCFBE", // MonoMac
-		"948B3504-5B70-4649-8FE4-BDE1FB46EC69",
-		"42C0BBD9-55CE-4FC1-8D90-A7348ABAFB23", // XamarinMac
-		"A3F8F2AB-B479-4A4A-A458-A89E7DC349F1":
+	// Xamarin.MacCatalyst has the Xamarin.Mac prefix
+	case framework == "maccatalyst" || strings.HasPrefix(framework, "xamarinmaccatalyst"):
+		return SDKMacCatalyst, nil
+	case framework == "macos" || strings.HasPrefix(framework, "xamarinmac"):
 		return SDKMacOS, nil
 	default:
-		return SDKUnknown, fmt.Errorf("Can not identify guid: %s", guid)
+		return SDKUnknown, fmt.Errorf("Can not identify target framework: %s", tfm)
+	}
+}
+
+// AndroidPackageFormat ...
+type AndroidPackageFormat string
+
+const (
+	// AndroidPackageFormatProject leaves the package format to the project's AndroidPackageFormat property.
+	AndroidPackageFormatProject AndroidPackageFormat = "project"
+	// AndroidPackageFormatAPK ...
+	AndroidPackageFormatAPK AndroidPackageFormat = "apk"
+	// AndroidPackageFormatAAB ...
+	AndroidPackageFormatAAB AndroidPackageFormat = "aab"
+	// AndroidPackageFormatBoth ...
+	AndroidPackageFormatBoth AndroidPackageFormat = "both"
+)
+
+// ParseAndroidPackageFormat ...
+func ParseAndroidPackageFormat(format string) (AndroidPackageFormat, error) {
+	switch format {
+	case "", "project":
+		return AndroidPackageFormatProject, nil
+	case "apk":
+		return AndroidPackageFormatAPK, nil
+	case "aab":
+		return AndroidPackageFormatAAB, nil
+	case "both":
+		return AndroidPackageFormatBoth, nil
+	default:
+		return AndroidPackageFormatProject, fmt.Errorf("invalid android package format: %s", format)
 	}
 }
 
@@ -130,6 +176,10 @@ const (
 	OutputTypeAPP OutputType = "app"
 	// OutputTypeDLL ...
 	OutputTypeDLL OutputType = "dll"
+	// OutputTypeMapping is the R8/ProGuard mapping.txt of an Android build.
+	OutputTypeMapping OutputType = "mapping"
+	// OutputTypeNativeSymbols is a native-debug-symbols.zip, or a directory of <abi>/*.so native libraries.
+	OutputTypeNativeSymbols OutputType = "native-symbols"
 )
 
 // ParseOutputType ...
@@ -151,6 +201,10 @@ func ParseOutputType(outputType string) (OutputType, error) {
 		return OutputTypeAPP, nil
 	case "dll":
 		return OutputTypeDLL, nil
+	case "mapping":
+		return OutputTypeMapping, nil
+	case "native-symbols":
+		return OutputTypeNativeSymbols, nil
 	default:
 		return OutputTypeUnknown, fmt.Errorf("invalid output type: %s", outputType)
 	}
@@ -0,0 +1,95 @@
+package constants
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProjectKind ...
+type ProjectKind string
+
+const (
+	// ProjectKindUnknown ...
+	ProjectKindUnknown ProjectKind = "unknown"
+	// ProjectKindApp ...
+	ProjectKindApp ProjectKind = "app"
+	// ProjectKindLibrary ...
+	ProjectKindLibrary ProjectKind = "library"
+	// ProjectKindBinding ...
+	ProjectKindBinding ProjectKind = "binding"
+	// ProjectKindAppExtension ...
+	ProjectKindAppExtension ProjectKind = "app-extension"
+	// ProjectKindWatchApp ...
+	ProjectKindWatchApp ProjectKind = "watch-app"
+	// ProjectKindWatchExtension ...
+	ProjectKindWatchExtension ProjectKind = "watch-extension"
+)
+
+// IsEmbedded reports if the projects of the kind are built into a host app, instead of being archived on their own.
+func (kind ProjectKind) IsEmbedded() bool {
+	return kind == ProjectKindAppExtension || kind == ProjectKindWatchApp || kind == ProjectKindWatchExtension
+}
+
+// ProjectType is a project type GUID with the platform and the kind of the projects it marks.
+// ProjectKindUnknown means the GUID is shared by the apps and the libraries of the platform,
+// the project properties (OutputType, AndroidApplication) tell them apart.
+type ProjectType struct {
+	GUID string
+	Name string
+	SDK  SDK
+	Kind ProjectKind
+}
+
+// ProjectTypes is the registry of the known project type GUIDs.
+// The watchOS projects are embedded into iOS apps, so they belong to the iOS platform.
+// The tvOS app extensions have no GUID of their own: they are tvOS projects with the IsAppExtension property.
+// The Mac Catalyst projects have no GUID at all, they are SDK-style projects identified by their target framework.
+var ProjectTypes = []ProjectType{
+	// Android
+	{GUID: "EFBA0AD7-5A72-4C68-AF49-83D382785DCF", Name: "Xamarin.Android", SDK: SDKAndroid, Kind: ProjectKindUnknown},
+	{GUID: "10368E6C-D01B-4462-8E8B-01FC667A7035", Name: "Xamarin.Android Binding", SDK: SDKAndroid, Kind: ProjectKindBinding},
+
+	// iOS
+	{GUID: "FEACFBD2-3405-455C-9665-78FE426C6842", Name: "Xamarin.iOS", SDK: SDKIOS, Kind: ProjectKindUnknown},
+	{GUID: "E613F3A2-FE9C-494F-B74E-F63BCB86FEA6", Name: "MonoTouch", SDK: SDKIOS, Kind: ProjectKindUnknown},
+	{GUID: "6BC8ED88-2882-458C-8E55-DFD12B67127B", Name: "MonoTouch", SDK: SDKIOS, Kind: ProjectKindUnknown},
+	{GUID: "F5B4F3BC-B597-4E2B-B552-EF5D8A32436F", Name: "MonoTouch Binding", SDK: SDKIOS, Kind: ProjectKindBinding},
+	{GUID: "8FFB629D-F513-41CE-95D2-7ECE97B6EEEC", Name: "Xamarin.iOS Binding", SDK: SDKIOS, Kind: ProjectKindBinding},
+	{GUID: "EE2C853D-36AF-4FDB-B1AD-8E90477E2198", Name: "Xamarin.iOS App Extension", SDK: SDKIOS, Kind: ProjectKindAppExtension},
+
+	// watchOS
+	{GUID: "FC6F6DD7-4CA3-4A23-A69C-F2D8F8745D10", Name: "Xamarin.watchOS App", SDK: SDKIOS, Kind: ProjectKindWatchApp},
+	{GUID: "1E2E965C-F6D2-49ED-B86E-418A60C69EEF", Name: "Xamarin.watchOS Extension", SDK: SDKIOS, Kind: ProjectKindWatchExtension},
+
+	// tvOS
+	{GUID: "06FA79CB-D6CD-4721-BB4B-1BD202089C55", Name: "Xamarin.tvOS", SDK: SDKTvOS, Kind: ProjectKindUnknown},
+	{GUID: "4A1ED743-3331-459B-915A-4B17C7E6CBAA", Name: "Xamarin.tvOS Binding", SDK: SDKTvOS, Kind: ProjectKindBinding},
+
+	// macOS
+	{GUID: "A3F8F2AB-B479-4A4A-A458-A89E7DC349F1", Name: "Xamarin.Mac", SDK: SDKMacOS, Kind: ProjectKindUnknown},
+	{GUID: "42C0BBD9-55CE-4FC1-8D90-A7348ABAFB23", Name: "Xamarin.Mac", SDK: SDKMacOS, Kind: ProjectKindUnknown},
+	{GUID: "1C533B1C-72DD-4CB1-9F6B-BF11D93BCFBE", Name: "MonoMac", SDK: SDKMacOS, Kind: ProjectKindUnknown},
+	{GUID: "948B3504-5B70-4649-8FE4-BDE1FB46EC69", Name: "MonoMac", SDK: SDKMacOS, Kind: ProjectKindUnknown},
+	{GUID: "810C163F-4746-4721-8B8E-88A3673A62EA", Name: "Xamarin.Mac Binding", SDK: SDKMacOS, Kind: ProjectKindBinding},
+}
+
+// LookupProjectType returns the registered project type of the given GUID, the GUID may be wrapped in braces.
+func LookupProjectType(guid string) (ProjectType, bool) {
+	guid = strings.ToUpper(strings.Trim(strings.TrimSpace(guid), "{}"))
+
+	for _, projectType := range ProjectTypes {
+		if projectType.GUID == guid {
+			return projectType, true
+		}
+	}
+	return ProjectType{}, false
+}
+
+// ParseProjectTypeGUID ...
+func ParseProjectTypeGUID(guid string) (SDK, error) {
+	projectType, ok := LookupProjectType(guid)
+	if !ok {
+		return SDKUnknown, fmt.Errorf("Can not identify guid: %s", guid)
+	}
+	return projectType.SDK, nil
+}
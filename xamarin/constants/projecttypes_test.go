@@ -0,0 +1,45 @@
+package constants
+
+import "testing"
+
+func TestLookupProjectType(t *testing.T) {
+	tests := []struct {
+		guid     string
+		wantSDK  SDK
+		wantKind ProjectKind
+		wantOk   bool
+	}{
+		{guid: "{FEACFBD2-3405-455C-9665-78FE426C6842}", wantSDK: SDKIOS, wantKind: ProjectKindUnknown, wantOk: true},
+		{guid: "ee2c853d-36af-4fdb-b1ad-8e90477e2198", wantSDK: SDKIOS, wantKind: ProjectKindAppExtension, wantOk: true},
+		{guid: " {1E2E965C-F6D2-49ED-B86E-418A60C69EEF} ", wantSDK: SDKIOS, wantKind: ProjectKindWatchExtension, wantOk: true},
+		{guid: "{4A1ED743-3331-459B-915A-4B17C7E6CBAA}", wantSDK: SDKTvOS, wantKind: ProjectKindBinding, wantOk: true},
+		{guid: "{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.guid, func(t *testing.T) {
+			projectType, ok := LookupProjectType(tt.guid)
+			if ok != tt.wantOk || projectType.SDK != tt.wantSDK || projectType.Kind != tt.wantKind {
+				t.Errorf("LookupProjectType() = %+v, %v, want %s, %s, %v", projectType, ok, tt.wantSDK, tt.wantKind, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseTargetFramework(t *testing.T) {
+	tests := map[string]SDK{
+		"net8.0-ios":          SDKIOS,
+		"net8.0-android34.0":  SDKAndroid,
+		"net8.0-maccatalyst":  SDKMacCatalyst,
+		"Xamarin.MacCatalyst": SDKMacCatalyst,
+		"Xamarin.Mac":         SDKMacOS,
+		"Xamarin.TVOS":        SDKTvOS,
+		"MonoAndroid90":       SDKAndroid,
+	}
+
+	for tfm, want := range tests {
+		if got, err := ParseTargetFramework(tfm); err != nil || got != want {
+			t.Errorf("ParseTargetFramework(%s) = %s, %v, want %s", tfm, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package logging is the logger of the Xamarin packages, which log through go-utils/log by default.
+package logging
+
+import "github.com/bitrise-io/go-utils/log"
+
+// Logger ...
+type Logger interface {
+	Warnf(format string, v ...interface{})
+	Debugf(format string, v ...interface{})
+}
+
+type defaultLogger struct{}
+
+// Warnf ...
+func (defaultLogger) Warnf(format string, v ...interface{}) {
+	log.Warnf(format, v...)
+}
+
+// Debugf ...
+func (defaultLogger) Debugf(format string, v ...interface{}) {
+	log.Debugf(format, v...)
+}
+
+var logger Logger = defaultLogger{}
+
+// SetLogger sets the logger of the Xamarin packages, for example to keep a structured log format.
+func SetLogger(l Logger) {
+	logger = l
+}
+
+// Warnf ...
+func Warnf(format string, v ...interface{}) {
+	logger.Warnf(format, v...)
+}
+
+// Debugf ...
+func Debugf(format string, v ...interface{}) {
+	logger.Debugf(format, v...)
+}
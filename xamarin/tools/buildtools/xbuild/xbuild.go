@@ -0,0 +1,256 @@
+package xbuild
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+)
+
+// Model ...
+type Model struct {
+	BuildTool string
+
+	SolutionPth string
+	ProjectPth  string
+
+	solutionDir string
+
+	target        string
+	configuration string
+	platform      string
+
+	buildIpa        bool
+	archiveOnBuild  bool
+	createPackage   bool
+	targetFramework string
+	archivePath     string
+
+	androidPackageFormats []string
+
+	codesignKey          string
+	codesignProvision    string
+	codesignEntitlements string
+
+	customOptions []string
+
+	timeout time.Duration
+}
+
+// New ...
+func New(solutionPth, projectPth string) (*Model, error) {
+	absSolutionPth, err := pathutil.AbsPath(solutionPth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to expand path (%s), error: %s", solutionPth, err)
+	}
+
+	absProjectPth := ""
+	if projectPth != "" {
+		absPth, err := pathutil.AbsPath(projectPth)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to expand path (%s), error: %s", projectPth, err)
+		}
+		absProjectPth = absPth
+	}
+
+	return &Model{SolutionPth: absSolutionPth, ProjectPth: absProjectPth, BuildTool: constants.XbuildPath}, nil
+}
+
+// SetSolutionDir sets the SolutionDir property, instead of the dir of the solution,
+// like the base solution's dir of a solution filter.
+func (xbuild *Model) SetSolutionDir(solutionDir string) *Model {
+	xbuild.solutionDir = solutionDir
+	return xbuild
+}
+
+// SetTarget ...
+func (xbuild *Model) SetTarget(target string) *Model {
+	xbuild.target = target
+	return xbuild
+}
+
+// SetConfiguration ...
+func (xbuild *Model) SetConfiguration(configuration string) *Model {
+	xbuild.configuration = configuration
+	return xbuild
+}
+
+// SetPlatform ...
+func (xbuild *Model) SetPlatform(platform string) *Model {
+	xbuild.platform = platform
+	return xbuild
+}
+
+// SetBuildIpa ...
+func (xbuild *Model) SetBuildIpa(buildIpa bool) *Model {
+	xbuild.buildIpa = buildIpa
+	return xbuild
+}
+
+// SetArchiveOnBuild ...
+func (xbuild *Model) SetArchiveOnBuild(archive bool) *Model {
+	xbuild.archiveOnBuild = archive
+	return xbuild
+}
+
+// SetCreatePackage sets if the Mac Catalyst and macOS builds create an installer package (.pkg).
+func (xbuild *Model) SetCreatePackage(createPackage bool) *Model {
+	xbuild.createPackage = createPackage
+	return xbuild
+}
+
+// SetTargetFramework sets the target framework to build, of the multi-targeting SDK-style projects.
+func (xbuild *Model) SetTargetFramework(targetFramework string) *Model {
+	xbuild.targetFramework = targetFramework
+	return xbuild
+}
+
+// SetArchivePath sets the directory the xcarchives are created in, instead of the Xcode archives dir.
+func (xbuild *Model) SetArchivePath(archivePath string) *Model {
+	xbuild.archivePath = archivePath
+	return xbuild
+}
+
+// SetAndroidPackageFormats sets the Android package formats (apk, aab) to build.
+// A single format is passed as AndroidPackageFormat, several formats as the .NET SDK's AndroidPackageFormats list.
+func (xbuild *Model) SetAndroidPackageFormats(formats ...string) *Model {
+	xbuild.androidPackageFormats = formats
+	return xbuild
+}
+
+// SetCodesignKey sets the signing identity (or its prefix, like iPhone Distribution) of the iOS and tvOS builds.
+func (xbuild *Model) SetCodesignKey(key string) *Model {
+	xbuild.codesignKey = key
+	return xbuild
+}
+
+// SetCodesignProvision sets the provisioning profile (name or UUID) of the iOS and tvOS builds.
+func (xbuild *Model) SetCodesignProvision(provision string) *Model {
+	xbuild.codesignProvision = provision
+	return xbuild
+}
+
+// SetCodesignEntitlements sets the entitlements file of the iOS and tvOS builds.
+func (xbuild *Model) SetCodesignEntitlements(entitlementsPth string) *Model {
+	xbuild.codesignEntitlements = entitlementsPth
+	return xbuild
+}
+
+// SetCustomOptions ...
+func (xbuild *Model) SetCustomOptions(options ...string) {
+	xbuild.customOptions = options
+}
+
+// SetTimeout ...
+func (xbuild *Model) SetTimeout(timeout time.Duration) {
+	xbuild.timeout = timeout
+}
+
+func (xbuild Model) buildCommands() []string {
+	cmdSlice := []string{xbuild.BuildTool}
+
+	if xbuild.ProjectPth != "" {
+		cmdSlice = append(cmdSlice, xbuild.ProjectPth)
+	} else {
+		cmdSlice = append(cmdSlice, xbuild.SolutionPth)
+	}
+
+	if xbuild.target != "" {
+		cmdSlice = append(cmdSlice, fmt.Sprintf("/target:%s", xbuild.target))
+	}
+
+	// According to official docs this value should include the trailing backslash:
+	// https://docs.microsoft.com/en-us/cpp/build/reference/common-macros-for-build-commands-and-properties?view=vs-2019
+	solutionDir := xbuild.solutionDir
+	if solutionDir == "" {
+		solutionDir = filepath.Dir(xbuild.SolutionPth)
+	}
+	solutionDirPth := ensureTrailingPathSeparator(solutionDir)
+	cmdSlice = append(cmdSlice, "/p:SolutionDir="+solutionDirPth)
+
+	if xbuild.configuration != "" {
+		cmdSlice = append(cmdSlice, "/p:Configuration="+xbuild.configuration)
+	}
+
+	if xbuild.platform != "" {
+		cmdSlice = append(cmdSlice, "/p:Platform="+xbuild.platform)
+	}
+
+	if xbuild.archiveOnBuild {
+		cmdSlice = append(cmdSlice, "/p:ArchiveOnBuild=true")
+	}
+
+	if xbuild.archivePath != "" {
+		cmdSlice = append(cmdSlice, "/p:ArchivePath="+xbuild.archivePath)
+	}
+
+	if xbuild.buildIpa {
+		cmdSlice = append(cmdSlice, "/p:BuildIpa=true")
+	}
+
+	if xbuild.createPackage {
+		cmdSlice = append(cmdSlice, "/p:CreatePackage=true")
+	}
+
+	if xbuild.targetFramework != "" {
+		cmdSlice = append(cmdSlice, "/p:TargetFramework="+xbuild.targetFramework)
+	}
+
+	switch len(xbuild.androidPackageFormats) {
+	case 0:
+	case 1:
+		cmdSlice = append(cmdSlice, "/p:AndroidPackageFormat="+xbuild.androidPackageFormats[0])
+	default:
+		// the list separator is escaped, a bare ; would separate the properties
+		cmdSlice = append(cmdSlice, "/p:AndroidPackageFormats="+strings.Join(xbuild.androidPackageFormats, "%3B"))
+	}
+
+	if xbuild.codesignKey != "" {
+		cmdSlice = append(cmdSlice, "/p:CodesignKey="+xbuild.codesignKey)
+	}
+
+	if xbuild.codesignProvision != "" {
+		cmdSlice = append(cmdSlice, "/p:CodesignProvision="+xbuild.codesignProvision)
+	}
+
+	if xbuild.codesignEntitlements != "" {
+		cmdSlice = append(cmdSlice, "/p:CodesignEntitlements="+xbuild.codesignEntitlements)
+	}
+
+	cmdSlice = append(cmdSlice, xbuild.customOptions...)
+
+	return cmdSlice
+}
+
+// String ...
+func (xbuild Model) String() string {
+	cmdSlice := xbuild.buildCommands()
+	return command.PrintableCommandArgs(true, cmdSlice)
+}
+
+// Run ...
+func (xbuild Model) Run(outWriter, errWriter io.Writer) error {
+	return xbuild.RunWithContext(context.Background(), outWriter, errWriter)
+}
+
+// RunWithContext ...
+func (xbuild Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	command, err := command.NewFromSlice(xbuild.buildCommands())
+	if err != nil {
+		return err
+	}
+
+	return tools.RunCommand(ctx, command.GetCmd(), xbuild.timeout, outWriter, errWriter)
+}
+
+func ensureTrailingPathSeparator(path string) string {
+	slash := string(filepath.Separator)
+	return strings.TrimSuffix(path, slash) + slash
+}
@@ -0,0 +1,107 @@
+package nuget
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
+)
+
+// Model ...
+type Model struct {
+	nugetPth string
+
+	solutionPth       string
+	solutionDirectory string
+	source            string
+	configFile        string
+
+	customOptions []string
+
+	timeout time.Duration
+}
+
+// New returns the restore command of the solution, or of a project if solutionPth is a project file.
+func New(solutionPth string) (*Model, error) {
+	absSolutionPth, err := pathutil.AbsPath(solutionPth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to expand path (%s), error: %s", solutionPth, err)
+	}
+
+	return &Model{nugetPth: constants.NugetPath, solutionPth: absSolutionPth}, nil
+}
+
+// SetSolutionDirectory sets the solution dir of a project's restore, the packages.config packages are restored into its packages dir.
+func (nuget *Model) SetSolutionDirectory(solutionDirectory string) *Model {
+	nuget.solutionDirectory = solutionDirectory
+	return nuget
+}
+
+// SetSource ...
+func (nuget *Model) SetSource(source string) *Model {
+	nuget.source = source
+	return nuget
+}
+
+// SetConfigFile ...
+func (nuget *Model) SetConfigFile(configFile string) *Model {
+	nuget.configFile = configFile
+	return nuget
+}
+
+// SetCustomOptions ...
+func (nuget *Model) SetCustomOptions(options ...string) {
+	nuget.customOptions = options
+}
+
+// SetTimeout ...
+func (nuget *Model) SetTimeout(timeout time.Duration) {
+	nuget.timeout = timeout
+}
+
+func (nuget Model) commandSlice() []string {
+	cmdSlice := []string{nuget.nugetPth, "restore", nuget.solutionPth}
+
+	if nuget.solutionDirectory != "" {
+		cmdSlice = append(cmdSlice, "-SolutionDirectory", nuget.solutionDirectory)
+	}
+
+	if nuget.source != "" {
+		cmdSlice = append(cmdSlice, "-Source", nuget.source)
+	}
+
+	if nuget.configFile != "" {
+		cmdSlice = append(cmdSlice, "-ConfigFile", nuget.configFile)
+	}
+
+	cmdSlice = append(cmdSlice, "-NonInteractive")
+	cmdSlice = append(cmdSlice, nuget.customOptions...)
+
+	return cmdSlice
+}
+
+// String ...
+func (nuget Model) String() string {
+	cmdSlice := nuget.commandSlice()
+	return command.PrintableCommandArgs(true, cmdSlice)
+}
+
+// Run ...
+func (nuget Model) Run(outWriter, errWriter io.Writer) error {
+	return nuget.RunWithContext(context.Background(), outWriter, errWriter)
+}
+
+// RunWithContext ...
+func (nuget Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	command, err := command.NewFromSlice(nuget.commandSlice())
+	if err != nil {
+		return err
+	}
+
+	return tools.RunCommand(ctx, command.GetCmd(), nuget.timeout, outWriter, errWriter)
+}
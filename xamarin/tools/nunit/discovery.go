@@ -0,0 +1,142 @@
+package nunit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+const consoleRunnerPackageID = "nunit.consolerunner"
+
+// Nunit3ConsolePath returns the nunit console path based on the NUNIT_PATH environment if it is set,
+// otherwise searches for it in the NuGet package folders with FindNunit3ConsolePath.
+func Nunit3ConsolePath(solutionDir string) (string, error) {
+	if os.Getenv("NUNIT_PATH") != "" {
+		return SystemNunit3ConsolePath()
+	}
+	return FindNunit3ConsolePath(solutionDir)
+}
+
+// FindNunit3ConsolePath searches for the highest version of nunit3-console.exe in
+// the solution's packages dir (packages/NUnit.ConsoleRunner.<version>/tools) and
+// in the global packages folder (NUGET_PACKAGES or ~/.nuget/packages/nunit.consolerunner/<version>/tools).
+func FindNunit3ConsolePath(solutionDir string) (string, error) {
+	candidates := map[string]string{} // nunit console path - package version
+
+	solutionPackagesDir := filepath.Join(solutionDir, "packages")
+	if err := collectConsoleRunners(solutionPackagesDir, consoleRunnerPackageID+".", candidates); err != nil {
+		return "", err
+	}
+
+	if globalPackagesDir := globalPackagesDir(); globalPackagesDir != "" {
+		if err := collectConsoleRunners(filepath.Join(globalPackagesDir, consoleRunnerPackageID), "", candidates); err != nil {
+			return "", err
+		}
+	}
+
+	nunitConsolePth := ""
+	version := ""
+	for pth, v := range candidates {
+		if nunitConsolePth == "" || compareVersions(v, version) > 0 {
+			nunitConsolePth = pth
+			version = v
+		}
+	}
+
+	if nunitConsolePth == "" {
+		return "", fmt.Errorf("%s not found in the NuGet package folders, set NUNIT_PATH or add the NUnit.ConsoleRunner package to the solution", nunit3Console)
+	}
+
+	return nunitConsolePth, nil
+}
+
+func globalPackagesDir() string {
+	if dir := os.Getenv("NUGET_PACKAGES"); dir != "" {
+		return dir
+	}
+
+	userHomeDir, ok := os.LookupEnv("HOME")
+	if !ok {
+		return ""
+	}
+	return filepath.Join(userHomeDir, ".nuget", "packages")
+}
+
+// collectConsoleRunners collects the nunit console paths from the package dirs named <prefix><version> in dir.
+func collectConsoleRunners(dir, prefix string, candidates map[string]string) error {
+	if exist, err := pathutil.IsDirExists(dir); err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() || !strings.HasPrefix(strings.ToLower(info.Name()), prefix) {
+			continue
+		}
+
+		version := info.Name()[len(prefix):]
+		if version == "" || version[0] < '0' || version[0] > '9' {
+			continue
+		}
+
+		nunitConsolePth := filepath.Join(dir, info.Name(), "tools", nunit3Console)
+		if exist, err := pathutil.IsPathExists(nunitConsolePth); err != nil {
+			return err
+		} else if exist {
+			candidates[nunitConsolePth] = version
+		}
+	}
+
+	return nil
+}
+
+// compareVersions compares NuGet package versions (for example 3.12.0 and 3.13.0-beta1),
+// returns a positive number if a is higher, negative if b is higher and 0 if they are equal.
+func compareVersions(a, b string) int {
+	aRelease, aPrerelease := splitPrerelease(a)
+	bRelease, bPrerelease := splitPrerelease(b)
+
+	aParts := strings.Split(aRelease, ".")
+	bParts := strings.Split(bRelease, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := 0, 0
+		if i < len(aParts) {
+			aPart, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bPart, _ = strconv.Atoi(bParts[i])
+		}
+		if aPart != bPart {
+			return aPart - bPart
+		}
+	}
+
+	// a release version is higher than its prereleases
+	switch {
+	case aPrerelease == bPrerelease:
+		return 0
+	case aPrerelease == "":
+		return 1
+	case bPrerelease == "":
+		return -1
+	}
+	return strings.Compare(aPrerelease, bPrerelease)
+}
+
+func splitPrerelease(version string) (string, string) {
+	if idx := strings.Index(version, "-"); idx != -1 {
+		return version[:idx], version[idx+1:]
+	}
+	return version, ""
+}
@@ -1,14 +1,17 @@
 package nunit
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/pathutil"
-	"github.com/bitrise-io/go-xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/constants"
+	"github.com/bitrise-steplib/steps-xamarin-archive/xamarin/tools"
 )
 
 const (
@@ -28,6 +31,8 @@ type Model struct {
 	resultLogPth string
 
 	customOptions []string
+
+	timeout time.Duration
 }
 
 // SystemNunit3ConsolePath ...
@@ -92,6 +97,11 @@ func (nunitConsole *Model) SetCustomOptions(options ...string) {
 	nunitConsole.customOptions = options
 }
 
+// SetTimeout ...
+func (nunitConsole *Model) SetTimeout(timeout time.Duration) {
+	nunitConsole.timeout = timeout
+}
+
 func (nunitConsole Model) commandSlice() []string {
 	cmdSlice := []string{constants.MonoPath}
 	cmdSlice = append(cmdSlice, nunitConsole.nunitConsolePth)
@@ -126,22 +136,15 @@ func (nunitConsole Model) String() string {
 
 // Run ...
 func (nunitConsole Model) Run(outWriter, errWriter io.Writer) error {
-	if outWriter == nil {
-		outWriter = os.Stdout
-	}
-	if errWriter == nil {
-		errWriter = os.Stderr
-	}
-
-	cmdSlice := nunitConsole.commandSlice()
+	return nunitConsole.RunWithContext(context.Background(), outWriter, errWriter)
+}
 
-	command, err := command.NewFromSlice(cmdSlice)
+// RunWithContext ...
+func (nunitConsole Model) RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error {
+	command, err := command.NewFromSlice(nunitConsole.commandSlice())
 	if err != nil {
 		return err
 	}
 
-	command.SetStdout(outWriter)
-	command.SetStderr(errWriter)
-
-	return command.Run()
+	return tools.RunCommand(ctx, command.GetCmd(), nunitConsole.timeout, outWriter, errWriter)
 }
@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// TimeoutOutputLines is the number of trailing output lines kept for a timed out command.
+const TimeoutOutputLines = 20
+
+// TerminateGracePeriod is the time a stopped command gets to exit before its process tree is killed.
+const TerminateGracePeriod = 10 * time.Second
+
+// errTimeout is returned by RunWithContext when the command was stopped because of its timeout.
+var errTimeout = errors.New("command timed out")
+
+// TimeoutLimit is the kind of time limit a command was stopped by.
+type TimeoutLimit string
+
+const (
+	// CommandTimeout is the time limit of a single command.
+	CommandTimeout TimeoutLimit = "command timeout"
+	// OverallTimeout is the time limit of a context created by WithTimeout, shared by all the commands run with it.
+	OverallTimeout TimeoutLimit = "overall timeout"
+)
+
+// TimeoutError ...
+type TimeoutError struct {
+	ProjectName string
+	Command     string
+	Limit       TimeoutLimit
+	Timeout     time.Duration // the configured value of the limit
+	LastLines   []string      // the last lines of the command's output
+}
+
+// Error ...
+func (e *TimeoutError) Error() string {
+	if e.ProjectName != "" {
+		return fmt.Sprintf("project (%s) build timed out after %s (%s), command: %s", e.ProjectName, e.Timeout, e.Limit, e.Command)
+	}
+	return fmt.Sprintf("command timed out after %s (%s): %s", e.Timeout, e.Limit, e.Command)
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of the parent context which is done after timeout, like context.WithTimeout.
+// The commands stopped by its deadline return a TimeoutError with the OverallTimeout limit and the timeout.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(parent, timeoutKey{}, timeout), timeout)
+}
+
+// ContextError returns the error of the done context: a TimeoutError of the given command
+// if the deadline of WithTimeout passed, the context's own error otherwise.
+func ContextError(ctx context.Context, command string) error {
+	err := ctx.Err()
+	if err != context.DeadlineExceeded {
+		return err
+	}
+
+	timeout, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	if !ok {
+		return err
+	}
+
+	return &TimeoutError{
+		Command: command,
+		Limit:   OverallTimeout,
+		Timeout: timeout,
+	}
+}
+
+// RunCommand runs the command with its output written into outWriter and errWriter (os.Stdout and os.Stderr if nil),
+// and stops it like RunWithContext. A timed out command's error is a TimeoutError with the last lines of its output.
+func RunCommand(ctx context.Context, cmd *exec.Cmd, timeout time.Duration, outWriter, errWriter io.Writer) error {
+	if outWriter == nil {
+		outWriter = os.Stdout
+	}
+	if errWriter == nil {
+		errWriter = os.Stderr
+	}
+
+	printableCommand := command.PrintableCommandArgs(true, cmd.Args)
+	if ctx.Err() != nil {
+		return ContextError(ctx, printableCommand)
+	}
+
+	if timeout <= 0 && ctx.Done() == nil {
+		cmd.Stdout = outWriter
+		cmd.Stderr = errWriter
+
+		return cmd.Run()
+	}
+
+	tail := NewTailWriter(TimeoutOutputLines)
+	cmd.Stdout = io.MultiWriter(outWriter, tail)
+	cmd.Stderr = io.MultiWriter(errWriter, tail)
+
+	err := RunWithContext(ctx, cmd, timeout)
+	if IsTimeout(err) {
+		return &TimeoutError{
+			Command:   printableCommand,
+			Limit:     CommandTimeout,
+			Timeout:   timeout,
+			LastLines: tail.Lines(),
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		err = ContextError(ctx, printableCommand)
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
+			timeoutErr.LastLines = tail.Lines()
+		}
+	}
+
+	return err
+}
+
+// RunWithContext runs the given command and stops its whole process tree
+// when the context is done or the command does not exit within timeout.
+// A zero timeout means no time limit.
+func RunWithContext(ctx context.Context, cmd *exec.Cmd, timeout time.Duration) error {
+	if timeout <= 0 && ctx.Done() == nil {
+		return cmd.Run()
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-timeoutC:
+		if err := stopProcessGroup(cmd, done); err != nil {
+			return fmt.Errorf("failed to stop timed out command, error: %s", err)
+		}
+		return errTimeout
+	case <-ctx.Done():
+		if err := stopProcessGroup(cmd, done); err != nil {
+			return fmt.Errorf("failed to stop cancelled command, error: %s", err)
+		}
+		return ctx.Err()
+	}
+}
+
+// stopProcessGroup asks the command's process tree to terminate and kills it
+// if it is still running after TerminateGracePeriod.
+func stopProcessGroup(cmd *exec.Cmd, done <-chan error) error {
+	if err := terminateProcessGroup(cmd); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(TerminateGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	if err := killProcessGroup(cmd); err != nil {
+		return err
+	}
+	<-done
+
+	return nil
+}
+
+// IsTimeout returns true if the error was returned by RunWithContext for a timed out command.
+func IsTimeout(err error) bool {
+	return err == errTimeout
+}
+
+// TailWriter keeps the last lines written into it.
+type TailWriter struct {
+	maxLines int
+
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+// NewTailWriter ...
+func NewTailWriter(maxLines int) *TailWriter {
+	return &TailWriter{maxLines: maxLines}
+}
+
+// Write ...
+func (w *TailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	split := strings.Split(w.partial+string(p), "\n")
+	w.partial = split[len(split)-1]
+
+	w.lines = append(w.lines, split[:len(split)-1]...)
+	if len(w.lines) > w.maxLines {
+		w.lines = w.lines[len(w.lines)-w.maxLines:]
+	}
+
+	return len(p), nil
+}
+
+// Lines ...
+func (w *TailWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := append([]string{}, w.lines...)
+	if w.partial != "" {
+		lines = append(lines, w.partial)
+	}
+	if len(lines) > w.maxLines {
+		lines = lines[len(lines)-w.maxLines:]
+	}
+	return lines
+}
@@ -1,6 +1,10 @@
 package tools
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // Runnable ...
 type Runnable interface {
@@ -9,6 +13,13 @@ type Runnable interface {
 	Run(outWriter, errWriter io.Writer) error
 }
 
+// ContextRunnable is a Runnable which can be stopped through a context,
+// together with its child processes.
+type ContextRunnable interface {
+	Runnable
+	RunWithContext(ctx context.Context, outWriter, errWriter io.Writer) error
+}
+
 // Printable ...
 type Printable interface {
 	String() string
@@ -19,7 +30,11 @@ type Editable interface {
 	SetCustomOptions(options ...string)
 }
 
-//
+// Timeoutable ...
+type Timeoutable interface {
+	SetTimeout(timeout time.Duration)
+}
+
 // EmptyCommand - for return type in case of failed to create a RunnableCommand
 type EmptyCommand struct{}
 
@@ -0,0 +1,62 @@
+package utility
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToConfig ...
+func ToConfig(configuration, platform string) string {
+	return fmt.Sprintf("%s|%s", configuration, platform)
+}
+
+// NormalizeConfig returns the comparable form of a Configuration|Platform key:
+// Visual Studio compares the configurations case-insensitively, ignoring the surrounding whitespaces,
+// and treats the Any CPU and AnyCPU platforms the same.
+func NormalizeConfig(config string) string {
+	parts := strings.Split(config, "|")
+	for i, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "any cpu" {
+			part = "anycpu"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "|")
+}
+
+// ConfigsEqual reports whether the given Configuration|Platform keys refer to the same configuration.
+func ConfigsEqual(config, otherConfig string) bool {
+	return NormalizeConfig(config) == NormalizeConfig(otherConfig)
+}
+
+// LookupConfig returns the value of the given Configuration|Platform key from a Configuration|Platform keyed map,
+// an exact key match is preferred over the normalized match.
+func LookupConfig(configMap map[string]string, config string) (string, bool) {
+	if value, ok := configMap[config]; ok {
+		return value, true
+	}
+
+	for key, value := range configMap {
+		if ConfigsEqual(key, config) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// FixWindowsPath ...
+func FixWindowsPath(pth string) string {
+	return strings.Replace(pth, `\`, "/", -1)
+}
+
+// SplitAndStripList ...
+func SplitAndStripList(list, separator string) []string {
+	split := strings.Split(list, separator)
+	var elements []string
+	for _, s := range split {
+		elements = append(elements, strings.TrimSpace(s))
+	}
+	return elements
+}